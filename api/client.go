@@ -13,18 +13,36 @@ type Client struct {
 	httpClient *http.Client
 }
 
-type Option (*Client)
+// Option configures the requests made by a Client
+type Option func(*clientOptions)
 
-// Options to create: WithHTTPClient, header manips, BaseURL, env handling
+type clientOptions struct {
+	requestOptions []option.RequestOption
+}
+
+// Options to create: WithHTTPClient, header manips, env handling
+
+// WithBaseURL sends the requests to another API endpoint than https://api.ngc.nvidia.com/
+func WithBaseURL(url string) Option {
+	return func(o *clientOptions) {
+		o.requestOptions = append(o.requestOptions, option.WithBaseURL(url))
+	}
+}
 
 func NewClient(apiKey string, opts ...Option) *Client {
-	return &Client{
-		Client: nvcf.NewClient(
+	o := clientOptions{
+		requestOptions: []option.RequestOption{
 			option.WithHeader("Content-Type", "application/json"),
 			option.WithHeader("Accept", "application/json"),
 			option.WithHeader("Authorization", "Bearer "+apiKey),
 			option.WithBaseURL("https://api.ngc.nvidia.com/"),
-		),
+		},
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Client{
+		Client: nvcf.NewClient(o.requestOptions...),
 		httpClient: &http.Client{
 			Timeout: time.Second * 30,
 		},
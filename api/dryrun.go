@@ -0,0 +1,206 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/tmc/nvcf-go/option"
+)
+
+var (
+	functionsPath  = regexp.MustCompile(`^/v2/nvcf/functions$`)
+	versionsPath   = regexp.MustCompile(`^/v2/nvcf/functions/([^/]+)/versions$`)
+	versionPath    = regexp.MustCompile(`^/v2/nvcf/functions/([^/]+)/versions/([^/]+)$`)
+	deploymentPath = regexp.MustCompile(`^/v2/nvcf/deployments/functions/([^/]+)/versions/([^/]+)$`)
+	invokePath     = regexp.MustCompile(`^/v2/nvcf/pexec/functions/([^/]+)/versions/([^/]+)$`)
+)
+
+// WithDryRun makes the client print every request that would change something
+// to w instead of sending it, and answer it with a plausible response. Created
+// functions and versions get placeholder IDs such as dry-run-version-1. Read-only
+// requests are still sent so that versions and defaults resolve exactly as in
+// a real run, except for versions the dry run itself created or changed, and
+// are printed in the sequence too, marked as executed.
+func WithDryRun(w io.Writer) Option {
+	d := &dryRun{out: w, functions: map[string]map[string]any{}, statuses: map[string]string{}}
+	return func(o *clientOptions) {
+		o.requestOptions = append(o.requestOptions, option.WithMiddleware(d.middleware))
+	}
+}
+
+type dryRun struct {
+	mu    sync.Mutex
+	out   io.Writer
+	calls int
+	// functions holds the versions created by the dry run, statuses the
+	// function status of every version the dry run deployed or stopped
+	functions map[string]map[string]any
+	statuses  map[string]string
+}
+
+func (d *dryRun) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	if req.Method == http.MethodGet {
+		if resp, ok := d.get(req); ok {
+			d.printRead(req, "answered by the dry run")
+			return resp, nil
+		}
+		d.printRead(req, "executed")
+		return next(req)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+	d.print(req, body)
+
+	var payload map[string]any
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, err
+		}
+	}
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodPost && functionsPath.MatchString(path):
+		return d.createVersion(req, fmt.Sprintf("dry-run-function-%d", d.calls), payload)
+	case req.Method == http.MethodPost && versionsPath.MatchString(path):
+		return d.createVersion(req, versionsPath.FindStringSubmatch(path)[1], payload)
+	case deploymentPath.MatchString(path):
+		ids := deploymentPath.FindStringSubmatch(path)
+		if req.Method == http.MethodDelete {
+			d.statuses[ids[1]+"/"+ids[2]] = "INACTIVE"
+			return jsonResponse(req, map[string]any{"function": map[string]any{"id": ids[1], "versionId": ids[2], "status": "INACTIVE"}})
+		}
+		d.statuses[ids[1]+"/"+ids[2]] = "ACTIVE"
+		if payload == nil {
+			payload = map[string]any{}
+		}
+		payload["functionId"], payload["functionVersionId"], payload["functionStatus"] = ids[1], ids[2], "DEPLOYING"
+		return jsonResponse(req, map[string]any{"deployment": payload})
+	case req.Method == http.MethodDelete && versionPath.MatchString(path):
+		ids := versionPath.FindStringSubmatch(path)
+		delete(d.functions, ids[1]+"/"+ids[2])
+		return &http.Response{StatusCode: http.StatusNoContent, Body: http.NoBody, Header: http.Header{}, Request: req}, nil
+	default:
+		return jsonResponse(req, map[string]any{})
+	}
+}
+
+// get answers read-only requests about versions the dry run created or changed.
+// It reports false for every other request, which is then sent.
+func (d *dryRun) get(req *http.Request) (*http.Response, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var body map[string]any
+	path := req.URL.Path
+	if ids := deploymentPath.FindStringSubmatch(path); ids != nil {
+		if status, ok := d.statuses[ids[1]+"/"+ids[2]]; ok {
+			body = map[string]any{"deployment": map[string]any{"functionId": ids[1], "functionVersionId": ids[2], "functionStatus": status}}
+		}
+	}
+	if ids := versionPath.FindStringSubmatch(path); ids != nil {
+		key := ids[1] + "/" + ids[2]
+		if function, ok := d.functions[key]; ok {
+			if status, ok := d.statuses[key]; ok {
+				function["status"] = status
+			}
+			body = map[string]any{"function": function}
+		}
+	}
+	if body == nil {
+		return nil, false
+	}
+	resp, err := jsonResponse(req, body)
+	return resp, err == nil
+}
+
+func (d *dryRun) createVersion(req *http.Request, functionID string, payload map[string]any) (*http.Response, error) {
+	if payload == nil {
+		payload = map[string]any{}
+	}
+	versionID := fmt.Sprintf("dry-run-version-%d", d.calls)
+	payload["id"], payload["versionId"], payload["status"] = functionID, versionID, "INACTIVE"
+	payload["createdAt"] = time.Now().UTC().Format(time.RFC3339)
+	d.functions[functionID+"/"+versionID] = payload
+	return jsonResponse(req, map[string]any{"function": payload})
+}
+
+// printRead prints a read-only request in the sequence, with how it was answered
+func (d *dryRun) printRead(req *http.Request, note string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.printTitle(req, note)
+}
+
+func (d *dryRun) print(req *http.Request, body []byte) {
+	note := "not sent"
+	if name := requestName(req); name != "" {
+		note = name + ", " + note
+	}
+	d.printTitle(req, note)
+	if len(body) > 0 {
+		var indented bytes.Buffer
+		if json.Indent(&indented, body, "", "  ") == nil {
+			body = indented.Bytes()
+		}
+		fmt.Fprintln(d.out, string(body))
+	}
+}
+
+func (d *dryRun) printTitle(req *http.Request, note string) {
+	if d.calls == 0 {
+		fmt.Fprintln(d.out, "Dry run: requests that would change something are printed and not sent. Read-only requests are sent. Nothing is changed.")
+	}
+	d.calls++
+	fmt.Fprintf(d.out, "%d. %s %s (%s)\n", d.calls, req.Method, req.URL.RequestURI(), note)
+}
+
+// requestName names the nvcf-go parameters sent by a request
+func requestName(req *http.Request) string {
+	path := req.URL.Path
+	switch {
+	case req.Method == http.MethodPost && functionsPath.MatchString(path):
+		return "FunctionNewParams"
+	case req.Method == http.MethodPost && versionsPath.MatchString(path):
+		return "FunctionVersionNewParams"
+	case req.Method == http.MethodPost && deploymentPath.MatchString(path):
+		return "InitiateDeploymentParams"
+	case req.Method == http.MethodPut && deploymentPath.MatchString(path):
+		return "UpdateDeploymentParams"
+	case req.Method == http.MethodDelete && deploymentPath.MatchString(path):
+		return "DeleteDeploymentParams"
+	case req.Method == http.MethodDelete && versionPath.MatchString(path):
+		return "delete function version"
+	case req.Method == http.MethodPost && invokePath.MatchString(path):
+		return "invoke function version"
+	}
+	return ""
+}
+
+func jsonResponse(req *http.Request, v any) (*http.Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDryRunPrintsEveryRequest(t *testing.T) {
+	var out bytes.Buffer
+	d := &dryRun{out: &out, functions: map[string]map[string]any{}, statuses: map[string]string{}}
+	sent := 0
+	next := func(req *http.Request) (*http.Response, error) {
+		sent++
+		return jsonResponse(req, map[string]any{"functions": []any{}})
+	}
+
+	requests := []*http.Request{
+		mustRequest(t, http.MethodGet, "/v2/nvcf/functions/fid/versions", ""),
+		mustRequest(t, http.MethodPost, "/v2/nvcf/functions/fid/versions", `{"name":"echo"}`),
+		mustRequest(t, http.MethodGet, "/v2/nvcf/functions/fid/versions/dry-run-version-2", ""),
+	}
+	for _, req := range requests {
+		if _, err := d.middleware(req, next); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if sent != 1 {
+		t.Errorf("%d request(s) sent, want only the first GET", sent)
+	}
+	for _, want := range []string{
+		"1. GET /v2/nvcf/functions/fid/versions (executed)",
+		"2. POST /v2/nvcf/functions/fid/versions (FunctionVersionNewParams, not sent)",
+		"3. GET /v2/nvcf/functions/fid/versions/dry-run-version-2 (answered by the dry run)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func mustRequest(t *testing.T, method, path, body string) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, "https://api.ngc.nvidia.com"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
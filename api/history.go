@@ -0,0 +1,60 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/brevdev/nvcf/state"
+	"github.com/tmc/nvcf-go"
+	"github.com/tmc/nvcf-go/option"
+)
+
+// WithHistory records every deployment and stop made through the client in the
+// deployment history under ~/.nvcf/state, which 'function rollback' reads. The
+// history is best effort: failing to record never fails the request.
+func WithHistory() Option {
+	return func(o *clientOptions) {
+		o.requestOptions = append(o.requestOptions, option.WithMiddleware(recordHistory))
+	}
+}
+
+func recordHistory(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	ids := deploymentPath.FindStringSubmatch(req.URL.Path)
+	if ids == nil || req.Method == http.MethodGet {
+		return next(req)
+	}
+
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+
+	resp, err := next(req)
+	if err != nil || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp, err
+	}
+	functionID, versionID := ids[1], ids[2]
+	switch req.Method {
+	case http.MethodDelete:
+		_ = state.RecordStop(functionID, versionID)
+	case http.MethodPost, http.MethodPut:
+		var params struct {
+			DeploymentSpecifications []nvcf.DeploymentResponseDeploymentDeploymentSpecification `json:"deploymentSpecifications"`
+		}
+		if json.Unmarshal(body, &params) == nil {
+			_ = state.RecordDeployment(functionID, versionID, params.DeploymentSpecifications)
+		}
+	}
+	return resp, nil
+}
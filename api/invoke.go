@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tmc/nvcf-go/option"
+)
+
+// invokeBaseURL is where functions are invoked, which is not where they are managed
+const invokeBaseURL = "https://api.nvcf.nvidia.com/"
+
+// pollSeconds is how long NVCF holds an invocation before answering that it is still pending
+const pollSeconds = 30
+
+// Invocation is the result of invoking a function version
+type Invocation struct {
+	StatusCode int
+	Body       []byte
+}
+
+// InvokeVersion invokes a deployed function version with a JSON body and waits
+// for the result, polling while NVCF reports the request as pending. It only
+// returns an error if the request could not be made or the function answered
+// with an error status; ctx bounds the whole wait.
+func (c *Client) InvokeVersion(ctx context.Context, functionID, versionID string, body json.RawMessage) (*Invocation, error) {
+	pollSecondsHeader := option.WithHeader("NVCF-POLL-SECONDS", fmt.Sprint(pollSeconds))
+	var resp *http.Response
+	err := c.Post(ctx, fmt.Sprintf("v2/nvcf/pexec/functions/%s/versions/%s", functionID, versionID), body, &resp,
+		option.WithBaseURL(invokeBaseURL), pollSecondsHeader)
+	for err == nil && resp.StatusCode == http.StatusAccepted {
+		requestID := resp.Header.Get("NVCF-REQID")
+		resp.Body.Close()
+		if requestID == "" {
+			return nil, fmt.Errorf("invocation is pending but NVCF returned no request id")
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+		}
+		err = c.Get(ctx, "v2/nvcf/pexec/status/"+requestID, nil, &resp, option.WithBaseURL(invokeBaseURL), pollSecondsHeader)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Invocation{StatusCode: resp.StatusCode, Body: data}, nil
+}
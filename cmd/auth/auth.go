@@ -43,41 +43,78 @@ func AuthCmd() *cobra.Command {
 }
 
 func authLoginCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "login",
 		Short: "Authenticate with NVIDIA Cloud",
+		Long: `Authenticate with NVIDIA Cloud. The first org of the API key is used unless --org is set.
+With --profile, the API key and org are saved as a named profile for commands that work
+across orgs, such as 'nvcf function clone --to-profile', and the current login is left as is.
+When stdin is not a terminal, the API key is read from it instead of prompted for.`,
+		Example: `nvcf auth login
+nvcf auth login --profile prod --org prod-org
+echo "$NGC_API_KEY" | nvcf auth login`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			apiKey := output.Prompt("Enter your NVIDIA Cloud API key: ", true)
-
-			err := config.SetAPIKey(apiKey)
-			if err != nil {
-				return output.Error(cmd, "Error saving API key", err)
+			profile, _ := cmd.Flags().GetString("profile")
+			orgID, _ := cmd.Flags().GetString("org")
+			var apiKey string
+			switch {
+			case !output.IsTerminal(os.Stdin):
+				key, err := output.ReadLine("")
+				if err != nil {
+					return output.Error(cmd, "Error reading the API key from stdin", err)
+				}
+				apiKey = key
+			case !output.CanPrompt(cmd):
+				return output.Error(cmd, "Cannot prompt for the API key without input. Pipe it to stdin or set NGC_API_KEY instead", nil)
+			default:
+				apiKey = output.Prompt("Enter your NVIDIA Cloud API key: ", true)
 			}
-
-			// Use the API key to get the first org
-			client := api.NewClient(apiKey)
-			orgsInfo := map[string]interface{}{}
-			err = client.Get(cmd.Context(), "/v2/orgs", nil, &orgsInfo)
-			if err != nil {
-				return output.Error(cmd, "Failed to fetch organization information", err)
+			if apiKey == "" {
+				return output.Error(cmd, "No API key entered", nil)
 			}
 
-			organizations, ok := orgsInfo["organizations"].([]interface{})
-			if !ok || len(organizations) == 0 {
-				return output.Error(cmd, "No organizations found", nil)
+			if profile == "" {
+				err := config.SetAPIKey(apiKey)
+				if err != nil {
+					return output.Error(cmd, "Error saving API key", err)
+				}
 			}
 
-			firstOrg, ok := organizations[0].(map[string]interface{})
-			if !ok {
-				return output.Error(cmd, "Failed to parse organization information", nil)
+			if orgID == "" {
+				// Use the API key to get the first org
+				client := api.NewClient(apiKey)
+				orgsInfo := map[string]interface{}{}
+				err := client.Get(cmd.Context(), "/v2/orgs", nil, &orgsInfo)
+				if err != nil {
+					return output.Error(cmd, "Failed to fetch organization information", err)
+				}
+
+				organizations, ok := orgsInfo["organizations"].([]interface{})
+				if !ok || len(organizations) == 0 {
+					return output.Error(cmd, "No organizations found", nil)
+				}
+
+				firstOrg, ok := organizations[0].(map[string]interface{})
+				if !ok {
+					return output.Error(cmd, "Failed to parse organization information", nil)
+				}
+
+				orgID, ok = firstOrg["name"].(string)
+				if !ok {
+					return output.Error(cmd, "Organization ID not found", nil)
+				}
 			}
 
-			orgID, ok := firstOrg["name"].(string)
-			if !ok {
-				return output.Error(cmd, "Organization ID not found", nil)
+			if profile != "" {
+				err := config.SetProfile(profile, config.Profile{APIKey: apiKey, OrgID: orgID})
+				if err != nil {
+					return output.Error(cmd, "Error saving profile", err)
+				}
+				output.Success(cmd, fmt.Sprintf("Profile %s saved with organization ID: %s", profile, orgID))
+				return nil
 			}
 
-			err = config.SetOrgID(orgID)
+			err := config.SetOrgID(orgID)
 			if err != nil {
 				return output.Error(cmd, "Error saving Org ID", err)
 			}
@@ -87,6 +124,9 @@ func authLoginCmd() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().String("profile", "", "Save the credentials as this named profile instead of logging in")
+	cmd.Flags().String("org", "", "Org to use. Default is the first org of the API key")
+	return cmd
 }
 
 func authConfigureDockerCmd() *cobra.Command {
@@ -149,16 +189,30 @@ func authStatusCmd() *cobra.Command {
 			output.Success(cmd, "Authenticated")
 			fmt.Printf("User: %s (%s)\n", name, email)
 			fmt.Printf("Current Organization ID: %s\n", currentOrgID)
+			for _, name := range config.ProfileNames() {
+				profile, _ := config.GetProfile(name)
+				fmt.Printf("Profile %s: Organization ID %s\n", name, profile.OrgID)
+			}
 			return nil
 		},
 	}
 }
 
 func authLogoutCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "logout",
 		Short: "Logout from NVIDIA Cloud",
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+				if _, ok := config.GetProfile(profile); !ok {
+					return output.Error(cmd, fmt.Sprintf("Profile %s not found", profile), nil)
+				}
+				if err := config.DeleteProfile(profile); err != nil {
+					return output.Error(cmd, "Failed to delete profile", err)
+				}
+				output.Success(cmd, fmt.Sprintf("Profile %s deleted", profile))
+				return nil
+			}
 			if !config.IsAuthenticated() {
 				output.Info(cmd, "You are currently not logged in")
 				return nil
@@ -176,6 +230,8 @@ func authLogoutCmd() *cobra.Command {
 			return nil
 		},
 	}
+	cmd.Flags().String("profile", "", "Delete this named profile instead of logging out")
+	return cmd
 }
 
 var whoamiURL = "/v2/users/me"
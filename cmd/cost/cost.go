@@ -0,0 +1,210 @@
+package cost
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/brevdev/nvcf/output"
+	"github.com/brevdev/nvcf/pricing"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// CostCmd returns a cobra.Command for estimating what deployments cost from
+// the local pricing table. These commands do not call the NVCF API.
+func CostCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cost",
+		Short: "Estimate what deployments cost",
+		Long: `Estimate what deployments cost from a local pricing table. NVCF does not publish prices, so the
+table holds the hourly price of an instance per GPU and instance type, by default and per org.
+
+The table is read from ~/.nvcf/pricing.yaml, or from the file set in NVCF_PRICING_FILE:
+
+` + pricing.Example,
+	}
+
+	cmd.AddCommand(costEstimateCmd())
+
+	return cmd
+}
+
+func costEstimateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "estimate",
+		Short: "Estimate the hourly and monthly cost of a deployment",
+		Long: `Estimate the hourly and monthly cost of a deployment at its min and max instances, either from
+--gpu, --instance-type, --min-instances and --max-instances or from the functions of a spec file.
+A month is counted as 730 hours. Prices are looked up for the org set with --org, or the configured org.`,
+		Example: `nvcf cost estimate --gpu L40 --instance-type gl40_1.br20_2xlarge --min-instances 1 --max-instances 4
+nvcf cost estimate --file deploy.yaml --overlay prod.yaml`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE:         runCostEstimate,
+	}
+	cmd.Flags().String("gpu", "", "GPU type to use")
+	cmd.Flags().String("instance-type", "", "Instance type to use")
+	cmd.Flags().Int64("min-instances", 0, "Minimum number of instances")
+	cmd.Flags().Int64("max-instances", 1, "Maximum number of instances")
+	cmd.Flags().StringP("file", "f", "", "Path to a YAML file containing function specifications")
+	cmd.Flags().StringSlice("overlay", nil, "Spec file to deep-merge over the --file spec (can be used multiple times)")
+	cmd.Flags().StringArray("var", nil, "Variable for ${VAR} references in the --file spec, format: KEY=VALUE (can be used multiple times)")
+	cmd.Flags().String("org", "", "Org whose prices to use. Default is the configured org")
+	return cmd
+}
+
+// costTotal is the estimate of all deployments together
+type costTotal struct {
+	Currency   string  `json:"currency"`
+	MinHourly  float64 `json:"minHourly"`
+	MaxHourly  float64 `json:"maxHourly"`
+	MinMonthly float64 `json:"minMonthly"`
+	MaxMonthly float64 `json:"maxMonthly"`
+}
+
+// costLine is the estimate of one deployment
+type costLine struct {
+	Name         string `json:"name,omitempty"`
+	GPU          string `json:"gpu"`
+	InstanceType string `json:"instanceType"`
+	MinInstances int64  `json:"minInstances"`
+	MaxInstances int64  `json:"maxInstances"`
+	pricing.Estimate
+}
+
+func runCostEstimate(cmd *cobra.Command, args []string) error {
+	lines, err := deploymentsToEstimate(cmd)
+	if err != nil {
+		return err
+	}
+
+	table, err := pricing.Load()
+	if err != nil {
+		return pricingTableError(cmd, err)
+	}
+	orgID, _ := cmd.Flags().GetString("org")
+	if orgID == "" {
+		orgID = config.GetOrgID()
+	}
+
+	var total costTotal
+	for i := range lines {
+		line := &lines[i]
+		line.Estimate, err = table.Estimate(orgID, line.GPU, line.InstanceType, line.MinInstances, line.MaxInstances)
+		if err != nil {
+			return output.Error(cmd, fmt.Sprintf("%s%v", namePrefix(line.Name), err), nil)
+		}
+		total.MinHourly += line.MinHourly
+		total.MaxHourly += line.MaxHourly
+		total.MinMonthly += line.MinMonthly
+		total.MaxMonthly += line.MaxMonthly
+	}
+	total.Currency = table.Currency
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(map[string]any{"deployments": lines, "total": total}, "", "  ")
+		if err != nil {
+			return output.Error(cmd, "Error formatting JSON", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+
+	writer := tablewriter.NewWriter(cmd.OutOrStdout())
+	writer.SetHeader([]string{"Name", "GPU", "Instance Type", "Instances", "Per Instance/h", "Hourly (min-max)", "Monthly (min-max)"})
+	writer.SetBorder(false)
+	writer.SetAutoWrapText(false)
+	for _, line := range lines {
+		writer.Append([]string{
+			line.Name,
+			line.GPU,
+			line.InstanceType,
+			fmt.Sprintf("%d-%d", line.MinInstances, line.MaxInstances),
+			formatCost(line.InstanceHourly),
+			formatCost(line.MinHourly) + " - " + formatCost(line.MaxHourly),
+			formatCost(line.MinMonthly) + " - " + formatCost(line.MaxMonthly),
+		})
+	}
+	if len(lines) > 1 {
+		writer.SetFooter([]string{"Total", "", "", "", "",
+			formatCost(total.MinHourly) + " - " + formatCost(total.MaxHourly),
+			formatCost(total.MinMonthly) + " - " + formatCost(total.MaxMonthly),
+		})
+	}
+	writer.Render()
+	output.Info(cmd, fmt.Sprintf("Prices in %s, a month is %d hours", table.Currency, pricing.HoursPerMonth))
+	return nil
+}
+
+// deploymentsToEstimate reads the deployments from --file, or from the deployment flags
+func deploymentsToEstimate(cmd *cobra.Command) ([]costLine, error) {
+	file, _ := cmd.Flags().GetString("file")
+	if file == "" {
+		gpu, _ := cmd.Flags().GetString("gpu")
+		instanceType, _ := cmd.Flags().GetString("instance-type")
+		minInstances, _ := cmd.Flags().GetInt64("min-instances")
+		maxInstances, _ := cmd.Flags().GetInt64("max-instances")
+		if gpu == "" {
+			return nil, output.Error(cmd, "Either --gpu or --file is required", nil)
+		}
+		if minInstances < 0 || maxInstances < minInstances {
+			return nil, output.Error(cmd, "--min-instances and --max-instances must satisfy 0 <= min <= max", nil)
+		}
+		return []costLine{{GPU: gpu, InstanceType: instanceType, MinInstances: minInstances, MaxInstances: maxInstances}}, nil
+	}
+
+	for _, flag := range []string{"gpu", "instance-type", "min-instances", "max-instances"} {
+		if cmd.Flags().Changed(flag) {
+			return nil, output.Error(cmd, fmt.Sprintf("--%s cannot be combined with --file", flag), nil)
+		}
+	}
+	overlays, _ := cmd.Flags().GetStringSlice("overlay")
+	varFlags, _ := cmd.Flags().GetStringArray("var")
+	vars, err := functionspec.ParseVars(varFlags)
+	if err != nil {
+		return nil, output.Error(cmd, err.Error(), nil)
+	}
+	spec, err := functionspec.Load(file, functionspec.Options{Deploy: true, Vars: vars, Overlays: overlays})
+	if err != nil {
+		var validationErrs functionspec.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return nil, fmt.Errorf("%s\n%d problem(s) found", validationErrs, len(validationErrs))
+		}
+		return nil, output.Error(cmd, fmt.Sprintf("Error reading %s", file), err)
+	}
+	spec.ApplyDefaults()
+	var lines []costLine
+	for _, fn := range spec.Functions {
+		lines = append(lines, costLine{
+			Name:         fn.FnName,
+			GPU:          fn.InstGPUType,
+			InstanceType: fn.InstType,
+			MinInstances: fn.InstMin,
+			MaxInstances: fn.InstMax,
+		})
+	}
+	return lines, nil
+}
+
+func pricingTableError(cmd *cobra.Command, err error) error {
+	path, _ := pricing.Path()
+	if errors.Is(err, os.ErrNotExist) {
+		return output.Error(cmd, fmt.Sprintf("No pricing table found at %s. Create one like this:\n\n%s", path, pricing.Example), nil)
+	}
+	return output.Error(cmd, fmt.Sprintf("Error reading the pricing table: %v", err), nil)
+}
+
+func namePrefix(name string) string {
+	if name == "" {
+		return ""
+	}
+	return name + ": "
+}
+
+func formatCost(amount float64) string {
+	return fmt.Sprintf("%.2f", amount)
+}
@@ -0,0 +1,126 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fakeDeploymentPath = regexp.MustCompile(`^/v2/nvcf/deployments/functions/([^/]+)/versions/([^/]+)$`)
+	fakeVersionPath    = regexp.MustCompile(`^/v2/nvcf/functions/([^/]+)/versions/([^/]+)$`)
+)
+
+// fakeAPI is an in-memory NVCF API that creates and lists functions, deploys them and
+// keeps them DEPLOYING until their deployment is deleted. It records every request.
+type fakeAPI struct {
+	server *httptest.Server
+	// onPollDeployment is called on every poll of a deployment's status
+	onPollDeployment func()
+
+	mu       sync.Mutex
+	requests []string
+	created  int
+	deployed map[string]bool
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	f := &fakeAPI{deployed: map[string]bool{}}
+	f.server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeAPI) client() *api.Client {
+	return api.NewClient("test-key", api.WithBaseURL(f.server.URL+"/"))
+}
+
+func (f *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	var body any
+	status := http.StatusOK
+	switch ids := fakeDeploymentPath.FindStringSubmatch(r.URL.Path); {
+	case r.Method == http.MethodGet && r.URL.Path == "/v2/nvcf/functions":
+		var functions []any
+		for i := 1; i <= f.created; i++ {
+			functions = append(functions, map[string]any{"id": fmt.Sprintf("fid-%d", i), "versionId": fmt.Sprintf("vid-%d", i), "name": fmt.Sprintf("fn-%d", i), "status": "INACTIVE"})
+		}
+		body = map[string]any{"functions": functions}
+	case r.Method == http.MethodPost && r.URL.Path == "/v2/nvcf/functions":
+		f.created++
+		body = map[string]any{"function": map[string]any{"id": fmt.Sprintf("fid-%d", f.created), "versionId": fmt.Sprintf("vid-%d", f.created), "status": "INACTIVE"}}
+	case ids != nil && r.Method == http.MethodPost:
+		f.deployed[ids[1]+"/"+ids[2]] = true
+		body = map[string]any{"deployment": map[string]any{"functionId": ids[1], "functionVersionId": ids[2], "functionStatus": "DEPLOYING"}}
+	case ids != nil && r.Method == http.MethodDelete:
+		delete(f.deployed, ids[1]+"/"+ids[2])
+		body = map[string]any{"function": map[string]any{"id": ids[1], "versionId": ids[2], "status": "INACTIVE"}}
+	case ids != nil:
+		onPoll := f.onPollDeployment
+		f.mu.Unlock()
+		if onPoll != nil {
+			onPoll()
+		}
+		f.mu.Lock()
+		body = map[string]any{"deployment": map[string]any{"functionId": ids[1], "functionVersionId": ids[2], "functionStatus": "DEPLOYING"}}
+	default:
+		ids := fakeVersionPath.FindStringSubmatch(r.URL.Path)
+		switch {
+		case ids != nil && r.Method == http.MethodDelete:
+			status = http.StatusNoContent
+		case ids != nil:
+			versionStatus := "INACTIVE"
+			if f.deployed[ids[1]+"/"+ids[2]] {
+				versionStatus = "DEPLOYING"
+			}
+			body = map[string]any{"function": map[string]any{"id": ids[1], "versionId": ids[2], "status": versionStatus}}
+		default:
+			status = http.StatusNotFound
+		}
+	}
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if body != nil {
+		_ = json.NewEncoder(w).Encode(body)
+	}
+}
+
+// deployments returns how many versions are deployed
+func (f *fakeAPI) deployments() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.deployed)
+}
+
+// indexOf returns the position of a request such as "DELETE /v2/nvcf/functions/fid/versions/vid", or -1
+func (f *fakeAPI) indexOf(request string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.requests {
+		if r == request {
+			return i
+		}
+	}
+	return -1
+}
+
+// newTestCommand returns a command with the persistent flags of the root command
+func newTestCommand() *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	for _, name := range []string{"json", "quiet", "dry-run", "no-input", "verbose", "skip-validation"} {
+		cmd.Flags().Bool(name, false, "")
+	}
+	_ = cmd.Flags().Set("quiet", "true")
+	_ = cmd.Flags().Set("no-input", "true")
+	return cmd
+}
@@ -1,6 +1,8 @@
 package function
 
 import (
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/config"
 	"github.com/spf13/cobra"
 )
 
@@ -29,6 +31,29 @@ on your serverless functions.`,
 	cmd.AddCommand(functionDeployCmd())
 	cmd.AddCommand(functionStopCmd())
 	cmd.AddCommand(functionWatchCmd())
+	cmd.AddCommand(functionExportCmd())
+	cmd.AddCommand(functionRolloutCmd())
+	cmd.AddCommand(functionRollbackCmd())
+	cmd.AddCommand(functionScheduleCmd())
+	cmd.AddCommand(functionAutoscaleCmd())
+	cmd.AddCommand(functionCloneCmd())
+	cmd.AddCommand(functionPruneCmd())
+	cmd.AddCommand(functionVersionsCmd())
 
 	return cmd
 }
+
+// newClient returns an API client for cmd that records deployments in the
+// deployment history. With --dry-run, requests that would change anything are
+// printed instead of sent, and nothing is recorded.
+func newClient(cmd *cobra.Command) *api.Client {
+	return newClientWithKey(cmd, config.GetAPIKey())
+}
+
+// newClientWithKey is newClient for another API key, such as that of a profile
+func newClientWithKey(cmd *cobra.Command, apiKey string) *api.Client {
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		return api.NewClient(apiKey, api.WithDryRun(cmd.OutOrStdout()))
+	}
+	return api.NewClient(apiKey, api.WithHistory())
+}
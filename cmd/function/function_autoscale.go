@@ -0,0 +1,324 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+func functionAutoscaleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "autoscale <function-id>",
+		Short: "Scale a deployment on its queue depth",
+		Long: `Watch the queue depth and active instances of a deployed version and recommend, or with
+--apply set, the min and max instances that keep at most --target-queue-depth requests
+waiting per instance.
+
+The recommended min instances is the queue depth divided by the target, rounded up and
+kept within --min-bound and --max-bound. The min instances is only lowered while more
+instances are active than the queue needs, so that a short queue in front of instances
+that are still starting does not scale the deployment down. The max instances is raised
+to the min when needed and lowered to --max-bound, so NVCF keeps scaling between them.
+After a change is applied, no other change is made until --cooldown has passed.
+
+Without --version-id, the newest ACTIVE version is scaled. The queue is polled every
+--interval until interrupted, or once with --once.`,
+		Example: `nvcf function autoscale fid --target-queue-depth 10
+nvcf function autoscale fid --target-queue-depth 10 --max-bound 8 --apply --cooldown 10m`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFunctionAutoscale,
+	}
+	cmd.Flags().String("version-id", "", "The version to scale. Defaults to the newest ACTIVE version")
+	cmd.Flags().Int64("target-queue-depth", 0, "How many requests may wait in the queue per instance (required)")
+	cmd.Flags().Int64("min-bound", 0, "The lowest min instances to set")
+	cmd.Flags().Int64("max-bound", 0, "The highest max instances to set. Defaults to the current max instances")
+	cmd.Flags().Bool("apply", false, "Update the deployment instead of only recommending changes")
+	cmd.Flags().Duration("cooldown", 5*time.Minute, "How long to wait after applying a change before making another")
+	cmd.Flags().Duration("interval", 30*time.Second, "How often to check the queue")
+	cmd.Flags().Bool("once", false, "Check the queue once and exit")
+	cmd.Flags().Int("spec-index", 0, "Which deployment specification to scale (1-based) when the version has several. Without it, you are prompted")
+	_ = cmd.MarkFlagRequired("target-queue-depth")
+	return cmd
+}
+
+// autoscaleBounds limit the instances the autoscaler sets
+type autoscaleBounds struct {
+	TargetQueueDepth int64
+	MinBound         int64
+	MaxBound         int64
+}
+
+// recommendInstances returns the min and max instances that keep at most the
+// target queue depth per instance, within the bounds. A max bound of 0 keeps the
+// current max instances as the bound. The current min instances is kept, rather
+// than lowered, unless more instances are active than the queue needs.
+func recommendInstances(queueDepth, activeInstances int64, spec nvcf.DeploymentResponseDeploymentDeploymentSpecification, bounds autoscaleBounds) (int64, int64) {
+	maxBound := bounds.MaxBound
+	if maxBound == 0 {
+		maxBound = spec.MaxInstances
+	}
+	maxBound = max(maxBound, bounds.MinBound, 1)
+
+	desired := (queueDepth + bounds.TargetQueueDepth - 1) / bounds.TargetQueueDepth
+	minInstances := min(max(desired, bounds.MinBound), maxBound)
+	if minInstances < spec.MinInstances && activeInstances <= desired {
+		minInstances = min(spec.MinInstances, maxBound)
+	}
+	maxInstances := max(min(spec.MaxInstances, maxBound), minInstances, 1)
+	return minInstances, maxInstances
+}
+
+func runFunctionAutoscale(cmd *cobra.Command, args []string) error {
+	client := newClient(cmd)
+	functionID := args[0]
+	versionID, _ := cmd.Flags().GetString("version-id")
+	apply, _ := cmd.Flags().GetBool("apply")
+	cooldown, _ := cmd.Flags().GetDuration("cooldown")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	once, _ := cmd.Flags().GetBool("once")
+	var bounds autoscaleBounds
+	bounds.TargetQueueDepth, _ = cmd.Flags().GetInt64("target-queue-depth")
+	bounds.MinBound, _ = cmd.Flags().GetInt64("min-bound")
+	bounds.MaxBound, _ = cmd.Flags().GetInt64("max-bound")
+	if bounds.TargetQueueDepth < 1 {
+		return output.Error(cmd, "--target-queue-depth must be at least 1", nil)
+	}
+	if bounds.MinBound < 0 || (bounds.MaxBound != 0 && bounds.MaxBound < max(bounds.MinBound, 1)) {
+		return output.Error(cmd, "--min-bound must be at least 0 and --max-bound at least --min-bound and 1", nil)
+	}
+	if interval <= 0 {
+		return output.Error(cmd, "--interval must be positive", nil)
+	}
+
+	if versionID == "" {
+		versions, err := client.Functions.Versions.List(cmd.Context(), functionID)
+		if err != nil {
+			return output.Error(cmd, "Error listing function versions", err)
+		}
+		version, ok := newestActiveVersion(versions.Functions)
+		if !ok {
+			return output.Error(cmd, fmt.Sprintf("Function %s has no ACTIVE version to scale", functionID), nil)
+		}
+		versionID = version.VersionID
+	}
+	deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, versionID)
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Error reading the deployment of version %s", versionID), err)
+	}
+	specs := deployment.Deployment.DeploymentSpecifications
+	specIndex, _ := cmd.Flags().GetInt("spec-index")
+	switch {
+	case len(specs) == 0:
+		return output.Error(cmd, fmt.Sprintf("Version %s has no deployment specification", versionID), nil)
+	case specIndex > len(specs) || specIndex < 0:
+		return output.Error(cmd, fmt.Sprintf("--spec-index must be between 1 and %d", len(specs)), nil)
+	case specIndex == 0 && len(specs) == 1:
+		specIndex = 1
+	case specIndex == 0:
+		if specIndex, err = selectDeploymentSpec(cmd, specs); err != nil {
+			return err
+		}
+	}
+	scaler := &autoscaler{
+		client:     client,
+		functionID: functionID,
+		versionID:  versionID,
+		specIndex:  specIndex - 1,
+		bounds:     bounds,
+		apply:      apply,
+		cooldown:   cooldown,
+		log:        newAutoscaleLog(cmd, functionID, versionID),
+	}
+
+	if !apply {
+		output.Info(cmd, "Only recommending changes. Pass --apply to make them")
+	}
+	if once {
+		if err := scaler.check(cmd.Context()); err != nil {
+			return output.Error(cmd, fmt.Sprintf("Error checking the queue: %v", err), nil)
+		}
+		return nil
+	}
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	output.Info(cmd, fmt.Sprintf("Watching the queue of function %s version %s every %s. Press Ctrl-C to stop", functionID, versionID, interval))
+	for {
+		if err := scaler.check(ctx); err != nil && ctx.Err() == nil {
+			scaler.log.emit(autoscaleEvent{Action: autoscaleActionError, Error: err.Error()})
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return nil
+		}
+	}
+}
+
+// autoscaler compares the queue of a deployment with its min and max instances
+type autoscaler struct {
+	client     *api.Client
+	functionID string
+	versionID  string
+	specIndex  int
+	bounds     autoscaleBounds
+	apply      bool
+	cooldown   time.Duration
+	lastChange time.Time
+	log        *autoscaleLog
+}
+
+// actions of the autoscaler
+const (
+	autoscaleActionUnchanged   = "unchanged"
+	autoscaleActionRecommended = "recommended"
+	autoscaleActionApplied     = "applied"
+	autoscaleActionCooldown    = "cooldown"
+	autoscaleActionError       = "error"
+)
+
+// check samples the queue once and recommends or applies new min and max instances
+func (a *autoscaler) check(ctx context.Context) error {
+	queues, err := a.client.Queues.Functions.Versions.List(ctx, a.functionID, a.versionID)
+	if err != nil {
+		return fmt.Errorf("reading the queue: %w", err)
+	}
+	var queueDepth int64
+	for _, queue := range queues.Queues {
+		queueDepth += queue.QueueDepth
+	}
+	function, err := a.client.Functions.Versions.Get(ctx, a.functionID, a.versionID, nvcf.FunctionVersionGetParams{})
+	if err != nil {
+		return fmt.Errorf("reading the instances: %w", err)
+	}
+	var activeInstances int64
+	for _, instance := range function.Function.ActiveInstances {
+		if instance.InstanceStatus == nvcf.FunctionResponseFunctionActiveInstancesInstanceStatusActive {
+			activeInstances++
+		}
+	}
+	deployment, err := a.client.FunctionDeployment.Functions.Versions.GetDeployment(ctx, a.functionID, a.versionID)
+	if err != nil {
+		return fmt.Errorf("reading the deployment: %w", err)
+	}
+	specs := deployment.Deployment.DeploymentSpecifications
+	if a.specIndex >= len(specs) {
+		return fmt.Errorf("the deployment no longer has specification %d", a.specIndex+1)
+	}
+	spec := specs[a.specIndex]
+
+	minInstances, maxInstances := recommendInstances(queueDepth, activeInstances, spec, a.bounds)
+	event := autoscaleEvent{
+		QueueDepth:      queueDepth,
+		ActiveInstances: activeInstances,
+		GPU:             spec.GPU,
+		InstanceType:    spec.InstanceType,
+		From:            instanceRange{Min: spec.MinInstances, Max: spec.MaxInstances},
+		To:              instanceRange{Min: minInstances, Max: maxInstances},
+	}
+	switch {
+	case event.From == event.To:
+		event.Action = autoscaleActionUnchanged
+	case !a.apply:
+		event.Action = autoscaleActionRecommended
+	case time.Since(a.lastChange) < a.cooldown:
+		event.Action = autoscaleActionCooldown
+		until := a.lastChange.Add(a.cooldown)
+		event.Until = &until
+	default:
+		params := make([]nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParamsDeploymentSpecification, len(specs))
+		for i, s := range specs {
+			params[i] = nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParamsDeploymentSpecification{
+				GPU:                   nvcf.String(s.GPU),
+				InstanceType:          nvcf.String(s.InstanceType),
+				MinInstances:          nvcf.Int(s.MinInstances),
+				MaxInstances:          nvcf.Int(s.MaxInstances),
+				MaxRequestConcurrency: nvcf.Int(s.MaxRequestConcurrency),
+			}
+		}
+		params[a.specIndex].MinInstances = nvcf.Int(minInstances)
+		params[a.specIndex].MaxInstances = nvcf.Int(maxInstances)
+		_, err := a.client.FunctionDeployment.Functions.Versions.UpdateDeployment(ctx, a.functionID, a.versionID, nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParams{
+			DeploymentSpecifications: nvcf.F(params),
+		})
+		if err != nil {
+			return fmt.Errorf("updating the deployment: %w", err)
+		}
+		a.lastChange = time.Now()
+		event.Action = autoscaleActionApplied
+	}
+	a.log.emit(event)
+	return nil
+}
+
+// autoscaleEvent is a check of the autoscaler. With --json each event is
+// printed as a line of JSON.
+type autoscaleEvent struct {
+	Time            time.Time     `json:"time"`
+	FunctionID      string        `json:"functionId"`
+	VersionID       string        `json:"versionId"`
+	Action          string        `json:"action"`
+	QueueDepth      int64         `json:"queueDepth"`
+	ActiveInstances int64         `json:"activeInstances"`
+	GPU             string        `json:"gpu,omitempty"`
+	InstanceType    string        `json:"instanceType,omitempty"`
+	From            instanceRange `json:"from"`
+	To              instanceRange `json:"to"`
+	Until           *time.Time    `json:"until,omitempty"`
+	Error           string        `json:"error,omitempty"`
+}
+
+type autoscaleLog struct {
+	cmd        *cobra.Command
+	functionID string
+	versionID  string
+	asJSON     bool
+	quiet      bool
+}
+
+func newAutoscaleLog(cmd *cobra.Command, functionID, versionID string) *autoscaleLog {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	return &autoscaleLog{cmd: cmd, functionID: functionID, versionID: versionID, asJSON: asJSON, quiet: quiet}
+}
+
+// emit prints an event. --quiet only leaves applied changes and errors.
+func (l *autoscaleLog) emit(event autoscaleEvent) {
+	event.Time = time.Now()
+	event.FunctionID, event.VersionID = l.functionID, l.versionID
+	out := l.cmd.OutOrStdout()
+	if l.asJSON {
+		event.Time = event.Time.UTC()
+		if data, err := json.Marshal(event); err == nil {
+			fmt.Fprintln(out, string(data))
+		}
+		return
+	}
+	if l.quiet && event.Action != autoscaleActionApplied && event.Action != autoscaleActionError {
+		return
+	}
+
+	stamp := event.Time.Format(time.RFC3339)
+	if event.Action == autoscaleActionError {
+		fmt.Fprintf(l.cmd.ErrOrStderr(), "%s  Error: %s\n", stamp, event.Error)
+		return
+	}
+	sample := fmt.Sprintf("%s  queue %d, %d active instance(s), %s", stamp, event.QueueDepth, event.ActiveInstances, joinNonEmpty(event.GPU, event.InstanceType))
+	change := fmt.Sprintf("min %d -> %d, max %d -> %d", event.From.Min, event.To.Min, event.From.Max, event.To.Max)
+	switch event.Action {
+	case autoscaleActionUnchanged:
+		fmt.Fprintf(out, "%s: min %d, max %d, no change\n", sample, event.From.Min, event.From.Max)
+	case autoscaleActionRecommended:
+		fmt.Fprintf(out, "%s: recommend %s\n", sample, change)
+	case autoscaleActionCooldown:
+		fmt.Fprintf(out, "%s: would set %s, cooling down until %s\n", sample, change, event.Until.Format(time.TimeOnly))
+	case autoscaleActionApplied:
+		fmt.Fprintf(out, "%s: applied %s\n", sample, change)
+	}
+}
@@ -0,0 +1,39 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/tmc/nvcf-go"
+)
+
+func TestRecommendInstances(t *testing.T) {
+	tests := []struct {
+		name            string
+		queueDepth      int64
+		activeInstances int64
+		current         [2]int64
+		bounds          autoscaleBounds
+		want            [2]int64
+	}{
+		{name: "queue depth rounds up", queueDepth: 21, activeInstances: 2, current: [2]int64{1, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{3, 8}},
+		{name: "an exact multiple does not round up", queueDepth: 20, activeInstances: 2, current: [2]int64{1, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{2, 8}},
+		{name: "a single request needs an instance", queueDepth: 1, activeInstances: 1, current: [2]int64{1, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{1, 8}},
+		{name: "min bound", queueDepth: 0, activeInstances: 4, current: [2]int64{4, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10, MinBound: 2}, want: [2]int64{2, 8}},
+		{name: "max bound caps min and max", queueDepth: 500, activeInstances: 8, current: [2]int64{1, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10, MaxBound: 4}, want: [2]int64{4, 4}},
+		{name: "max bound defaults to the current max", queueDepth: 500, activeInstances: 6, current: [2]int64{1, 6}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{6, 6}},
+		{name: "max is raised to the min bound", queueDepth: 0, activeInstances: 1, current: [2]int64{0, 1}, bounds: autoscaleBounds{TargetQueueDepth: 10, MinBound: 3}, want: [2]int64{3, 3}},
+		{name: "max is at least 1", queueDepth: 0, activeInstances: 0, current: [2]int64{0, 0}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{0, 1}},
+		{name: "scales down when more instances are active than needed", queueDepth: 5, activeInstances: 4, current: [2]int64{4, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{1, 8}},
+		{name: "keeps the min while instances are starting", queueDepth: 5, activeInstances: 1, current: [2]int64{4, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10}, want: [2]int64{4, 8}},
+		{name: "keeps the min within the max bound while instances are starting", queueDepth: 0, activeInstances: 0, current: [2]int64{6, 8}, bounds: autoscaleBounds{TargetQueueDepth: 10, MaxBound: 4}, want: [2]int64{4, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := nvcf.DeploymentResponseDeploymentDeploymentSpecification{MinInstances: tt.current[0], MaxInstances: tt.current[1]}
+			minInstances, maxInstances := recommendInstances(tt.queueDepth, tt.activeInstances, spec, tt.bounds)
+			if got := [2]int64{minInstances, maxInstances}; got != tt.want {
+				t.Errorf("got min %d max %d, want min %d max %d", got[0], got[1], tt.want[0], tt.want[1])
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package function
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/output"
+	"github.com/brevdev/nvcf/pricing"
+	"github.com/spf13/cobra"
+)
+
+// budgetedDeployment is a deployment checked against --max-hourly-cost
+type budgetedDeployment struct {
+	Name         string
+	GPU          string
+	InstanceType string
+	MaxInstances int64
+}
+
+func addBudgetFlag(cmd *cobra.Command) {
+	cmd.Flags().Float64("max-hourly-cost", 0, "Refuse to deploy if the deployment could cost more than this per hour at its max instances, according to the pricing table (see 'nvcf cost --help')")
+}
+
+// checkHourlyBudget refuses deployments whose combined cost per hour at their
+// max instances exceeds --max-hourly-cost. Without the flag it does nothing.
+// A deployment without a price in the pricing table is refused too, since its
+// cost cannot be known.
+func checkHourlyBudget(cmd *cobra.Command, deployments ...budgetedDeployment) error {
+	budget, _ := cmd.Flags().GetFloat64("max-hourly-cost")
+	if budget <= 0 {
+		return nil
+	}
+
+	table, err := pricing.Load()
+	if err != nil {
+		path, _ := pricing.Path()
+		if errors.Is(err, os.ErrNotExist) {
+			return output.Error(cmd, fmt.Sprintf("--max-hourly-cost needs a pricing table, but none was found at %s. See 'nvcf cost --help' for its format", path), nil)
+		}
+		return output.Error(cmd, fmt.Sprintf("Error reading the pricing table: %v", err), nil)
+	}
+
+	var total float64
+	var lines []string
+	for _, deployment := range deployments {
+		estimate, err := table.Estimate(config.GetOrgID(), deployment.GPU, deployment.InstanceType, 0, deployment.MaxInstances)
+		if err != nil {
+			return output.Error(cmd, fmt.Sprintf("Cannot check --max-hourly-cost: %v", err), nil)
+		}
+		total += estimate.MaxHourly
+		lines = append(lines, fmt.Sprintf("  %s%d x %s at %.2f = %.2f %s/h",
+			deployment.label(), deployment.MaxInstances, joinNonEmpty(deployment.GPU, deployment.InstanceType),
+			estimate.InstanceHourly, estimate.MaxHourly, table.Currency))
+	}
+	if total > budget {
+		return output.Error(cmd, fmt.Sprintf("The deployment could cost %.2f %s/h at max instances, more than --max-hourly-cost %.2f:\n%s",
+			total, table.Currency, budget, strings.Join(lines, "\n")), nil)
+	}
+	return nil
+}
+
+func (d budgetedDeployment) label() string {
+	if d.Name == "" {
+		return ""
+	}
+	return d.Name + ": "
+}
@@ -0,0 +1,59 @@
+package function
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckHourlyBudget(t *testing.T) {
+	pricingFile := filepath.Join(t.TempDir(), "pricing.yaml")
+	if err := os.WriteFile(pricingFile, []byte("default:\n  - gpu: L40\n    hourly: 1.50\n  - gpu: H100\n    hourly: 4.00\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NVCF_PRICING_FILE", pricingFile)
+	echo := budgetedDeployment{Name: "echo", GPU: "L40", InstanceType: "gl40_1.br20_2xlarge", MaxInstances: 2}
+	chat := budgetedDeployment{Name: "chat", GPU: "H100", MaxInstances: 1}
+
+	tests := []struct {
+		name        string
+		budget      string
+		deployments []budgetedDeployment
+		want        string
+	}{
+		{name: "no budget", deployments: []budgetedDeployment{echo, chat}},
+		{name: "within the budget", budget: "3", deployments: []budgetedDeployment{echo}},
+		{name: "each within the budget, together over it", budget: "5", deployments: []budgetedDeployment{echo, chat},
+			want: "The deployment could cost 7.00 USD/h at max instances, more than --max-hourly-cost 5.00:\n  echo: 2 x L40 gl40_1.br20_2xlarge at 1.50 = 3.00 USD/h\n  chat: 1 x H100 at 4.00 = 4.00 USD/h"},
+		{name: "exactly the budget", budget: "7", deployments: []budgetedDeployment{echo, chat}},
+		{name: "no price", budget: "100", deployments: []budgetedDeployment{{GPU: "A100", MaxInstances: 1}},
+			want: "Cannot check --max-hourly-cost: the pricing table has no price for GPU A100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := newTestCommand()
+			addBudgetFlag(cmd)
+			if tt.budget != "" {
+				_ = cmd.Flags().Set("max-hourly-cost", tt.budget)
+			}
+			err := checkHourlyBudget(cmd, tt.deployments...)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("got %v, want no error", err)
+			case tt.want != "" && (err == nil || err.Error() != tt.want):
+				t.Errorf("got\n%v\nwant\n%s", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("no pricing table", func(t *testing.T) {
+		t.Setenv("NVCF_PRICING_FILE", filepath.Join(t.TempDir(), "missing.yaml"))
+		cmd := newTestCommand()
+		addBudgetFlag(cmd)
+		_ = cmd.Flags().Set("max-hourly-cost", "10")
+		if err := checkHourlyBudget(cmd, echo); err == nil || !strings.Contains(err.Error(), "needs a pricing table") {
+			t.Errorf("got %v, want a missing pricing table", err)
+		}
+	})
+}
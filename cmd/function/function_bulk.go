@@ -0,0 +1,294 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/collections"
+	"github.com/brevdev/nvcf/flagutil"
+	"github.com/brevdev/nvcf/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// outcomes of a version in a bulk operation
+const (
+	bulkStatusDone    = "done"
+	bulkStatusSkipped = "skipped"
+	bulkStatusFailed  = "failed"
+)
+
+// versionSelector picks function versions by tags, name, status and age. An
+// empty field matches every version.
+type versionSelector struct {
+	Tags      []string
+	Name      string
+	Statuses  []nvcf.ListFunctionsResponseFunctionsStatus
+	OlderThan time.Duration
+}
+
+// bulkResult is the outcome of a bulk operation for one version
+type bulkResult struct {
+	FunctionID string `json:"functionId"`
+	VersionID  string `json:"versionId"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// addSelectorFlags adds the flags that select the versions a bulk operation acts on
+func addSelectorFlags(cmd *cobra.Command, defaultStatus string) {
+	statusUsage := "Only act on versions with this status (ACTIVE, DEPLOYING, ERROR, INACTIVE). Can be repeated"
+	if defaultStatus != "" {
+		statusUsage += fmt.Sprintf(". Defaults to %s when selecting", defaultStatus)
+	}
+	cmd.Flags().StringSlice("tag", nil, "Only act on versions with this tag, such as team=search. Can be repeated; every tag must match")
+	cmd.Flags().String("name", "", "Only act on versions whose name matches this glob, such as 'search-*'")
+	cmd.Flags().StringSlice("status", nil, statusUsage)
+	cmd.Flags().String("older-than", "", "Only act on versions created longer ago than this age, such as 30d, 2w or 12h")
+	cmd.Flags().Int("parallel", 4, "How many versions to act on at once when selecting")
+}
+
+// selectorFromFlags reads the selector flags. It reports false if none is set.
+func selectorFromFlags(cmd *cobra.Command, defaultStatus nvcf.ListFunctionsResponseFunctionsStatus) (versionSelector, bool, error) {
+	selecting := false
+	for _, name := range []string{"tag", "name", "status", "older-than"} {
+		selecting = selecting || cmd.Flags().Changed(name)
+	}
+	if !selecting {
+		return versionSelector{}, false, nil
+	}
+
+	var selector versionSelector
+	selector.Tags, _ = cmd.Flags().GetStringSlice("tag")
+	selector.Name, _ = cmd.Flags().GetString("name")
+	if _, err := path.Match(selector.Name, ""); err != nil {
+		return selector, true, output.Error(cmd, fmt.Sprintf("Invalid --name pattern %q", selector.Name), nil)
+	}
+	statuses, _ := cmd.Flags().GetStringSlice("status")
+	for _, value := range statuses {
+		status := nvcf.ListFunctionsResponseFunctionsStatus(strings.ToUpper(value))
+		if !status.IsKnown() {
+			return selector, true, output.Error(cmd, fmt.Sprintf("Invalid status: '%s'", value), nil)
+		}
+		selector.Statuses = append(selector.Statuses, status)
+	}
+	if len(selector.Statuses) == 0 && defaultStatus != "" {
+		selector.Statuses = []nvcf.ListFunctionsResponseFunctionsStatus{defaultStatus}
+	}
+	if olderThan, _ := cmd.Flags().GetString("older-than"); olderThan != "" {
+		age, err := flagutil.ParseAge(olderThan)
+		if err != nil {
+			return selector, true, output.Error(cmd, err.Error(), nil)
+		}
+		selector.OlderThan = age
+	}
+	return selector, true, nil
+}
+
+// bulkMode reports whether a command acts on the versions picked by the selector
+// flags rather than on one function. Without a function ID, selecting is required.
+func bulkMode(cmd *cobra.Command, args []string, defaultStatus nvcf.ListFunctionsResponseFunctionsStatus) (versionSelector, bool, error) {
+	selector, selecting, err := selectorFromFlags(cmd, defaultStatus)
+	if err != nil {
+		return selector, false, err
+	}
+	if !selecting {
+		if len(args) == 0 {
+			return selector, false, output.Error(cmd, "Pass a function ID, or select versions with --tag, --name, --status or --older-than", nil)
+		}
+		return selector, false, nil
+	}
+	for _, name := range []string{"version-id", "all"} {
+		if cmd.Flags().Changed(name) {
+			return selector, false, output.Error(cmd, fmt.Sprintf("--%s cannot be combined with --tag, --name, --status or --older-than", name), nil)
+		}
+	}
+	return selector, true, nil
+}
+
+// matches reports whether a version is selected
+func (s versionSelector) matches(version nvcf.ListFunctionsResponseFunction, now time.Time) bool {
+	for _, tag := range s.Tags {
+		if !collections.ListContains(version.Tags, tag) {
+			return false
+		}
+	}
+	if s.Name != "" {
+		if ok, _ := path.Match(s.Name, version.Name); !ok {
+			return false
+		}
+	}
+	if len(s.Statuses) > 0 && !collections.ListContains(s.Statuses, version.Status) {
+		return false
+	}
+	return s.OlderThan == 0 || version.CreatedAt.Before(now.Add(-s.OlderThan))
+}
+
+// selectVersions lists the versions of a function, or of every private function
+// when functionID is empty. It returns the selected versions and every listed
+// version, which protection is checked against.
+func selectVersions(ctx context.Context, client *api.Client, functionID string, selector versionSelector) ([]nvcf.ListFunctionsResponseFunction, []nvcf.ListFunctionsResponseFunction, error) {
+	var listed *nvcf.ListFunctionsResponse
+	var err error
+	if functionID != "" {
+		listed, err = client.Functions.Versions.List(ctx, functionID)
+	} else {
+		listed, err = client.Functions.List(ctx, nvcf.FunctionListParams{
+			Visibility: nvcf.F([]nvcf.FunctionListParamsVisibility{nvcf.FunctionListParamsVisibilityPrivate}),
+		})
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	selected := collections.Filter(listed.Functions, func(version nvcf.ListFunctionsResponseFunction) bool {
+		return selector.matches(version, now)
+	})
+	sort.SliceStable(selected, func(i, j int) bool {
+		if selected[i].Name != selected[j].Name {
+			return selected[i].Name < selected[j].Name
+		}
+		return selected[i].CreatedAt.Before(selected[j].CreatedAt)
+	})
+	return selected, listed.Functions, nil
+}
+
+// runBulk applies op to the versions a selector picks, a few at a time. Versions of
+// protected functions are skipped unless --override-protection is set. It asks for
+// confirmation, shows progress, and reports the outcome of every version.
+func runBulk(cmd *cobra.Command, client *api.Client, functionID, action, done string, selector versionSelector, op func(ctx context.Context, version nvcf.ListFunctionsResponseFunction) error) error {
+	selected, listed, err := selectVersions(cmd.Context(), client, functionID, selector)
+	if err != nil {
+		return output.Error(cmd, "Error listing function versions", err)
+	}
+	return runBulkVersions(cmd, action, done, selected, listed, op)
+}
+
+// runBulkVersions is runBulk for versions that have already been selected.
+// listed holds every version of their functions, which protection is checked against.
+func runBulkVersions(cmd *cobra.Command, action, done string, selected, listed []nvcf.ListFunctionsResponseFunction, op func(ctx context.Context, version nvcf.ListFunctionsResponseFunction) error) error {
+	if len(selected) == 0 {
+		output.Info(cmd, fmt.Sprintf("No versions match, nothing to %s", action))
+		return nil
+	}
+
+	override, _ := cmd.Flags().GetBool("override-protection")
+	protection := map[string]string{}
+	for _, version := range selected {
+		if _, ok := protection[version.ID]; ok {
+			continue
+		}
+		versions := collections.Filter(listed, func(v nvcf.ListFunctionsResponseFunction) bool {
+			return v.ID == version.ID
+		})
+		protection[version.ID] = protectionReason(version.ID, versions)
+	}
+
+	lines := []string{fmt.Sprintf("This will %s %d version(s):", action, len(selected))}
+	for _, version := range selected {
+		line := fmt.Sprintf("  %s  %s/%s  %-9s  created %s", version.Name, version.ID, version.VersionID, version.Status, version.CreatedAt.Format(time.RFC3339))
+		if reason := protection[version.ID]; reason != "" {
+			if override {
+				line += "  (protected, proceeding because of --override-protection)"
+			} else {
+				line += "  (protected, skipped)"
+			}
+		}
+		lines = append(lines, line)
+	}
+	if yes, _ := cmd.Flags().GetBool("yes"); !yes {
+		confirmed, err := output.Confirm(cmd, strings.Join(lines, "\n"))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			output.Info(cmd, fmt.Sprintf("Nothing to %s", action))
+			return nil
+		}
+	}
+
+	labels := make([]string, len(selected))
+	for i, version := range selected {
+		labels[i] = fmt.Sprintf("%s %s", version.Name, version.VersionID)
+	}
+	progress := output.NewProgress(cmd, labels)
+	progress.Start()
+
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	results := make([]bulkResult, len(selected))
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, version := range selected {
+		results[i] = bulkResult{FunctionID: version.ID, VersionID: version.VersionID, Name: version.Name}
+		if reason := protection[version.ID]; reason != "" && !override {
+			results[i].Status, results[i].Error = bulkStatusSkipped, fmt.Sprintf("protected because %s", reason)
+			progress.Skip(i, "skipped, protected")
+			continue
+		}
+		wg.Add(1)
+		go func(i int, version nvcf.ListFunctionsResponseFunction) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			progress.Update(i, "in progress")
+			if err := op(cmd.Context(), version); err != nil {
+				results[i].Status, results[i].Error = bulkStatusFailed, err.Error()
+				progress.Finish(i, bulkStatusFailed, err)
+				return
+			}
+			results[i].Status = bulkStatusDone
+			progress.Finish(i, done, nil)
+		}(i, version)
+	}
+	wg.Wait()
+	progress.Stop()
+
+	printBulkSummary(cmd, results, done)
+	failed := len(collections.Filter(results, func(result bulkResult) bool {
+		return result.Status == bulkStatusFailed
+	}))
+	if failed > 0 {
+		return fmt.Errorf("%d of %d version(s) could not be %s", failed, len(results), done)
+	}
+	return nil
+}
+
+// printBulkSummary prints the outcome of every version and the counts of each outcome
+func printBulkSummary(cmd *cobra.Command, results []bulkResult, done string) {
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		}
+		return
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		return
+	}
+
+	counts := map[string]int{}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Name", "Status", "Function ID", "Version ID", "Error"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, result := range results {
+		counts[result.Status]++
+		status := result.Status
+		if status == bulkStatusDone {
+			status = done
+		}
+		table.Append([]string{result.Name, status, result.FunctionID, result.VersionID, result.Error})
+	}
+	fmt.Fprintln(cmd.OutOrStdout())
+	table.Render()
+	fmt.Fprintf(cmd.OutOrStdout(), "\n%d %s, %d skipped, %d failed\n", counts[bulkStatusDone], done, counts[bulkStatusSkipped], counts[bulkStatusFailed])
+}
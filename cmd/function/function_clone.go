@@ -0,0 +1,356 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/cmd/gpu"
+	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// registryHosts are the NGC registries whose paths start with the org name
+var registryHosts = []string{"nvcr.io/", "helm.ngc.nvidia.com/"}
+
+func functionCloneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clone <function-id>",
+		Short: "Copy a function version to the org of another profile",
+		Long: `Recreate a function version as a new function in the org of a profile saved with
+'nvcf auth login --profile'. Container images on nvcr.io and helm charts on helm.ngc.nvidia.com
+under the source org are rewritten to the target org, so push them there first.
+
+The API returns only the names of secrets, never their values: pass the values with --secret,
+and secrets without one are left out with a warning.
+
+With --deploy, the deployment specifications of the source version are copied too. A backend
+the target org lacks is remapped with --backend-map, or else to a backend of the target org
+offering the same GPU and instance type.`,
+		Example: `nvcf function clone fid --to-profile prod
+nvcf function clone fid --version-id vid --to-profile prod --deploy --secret HF_TOKEN=hf_xxx
+nvcf function clone fid --to-profile prod --deploy --backend-map GFN=dgxc-forge-az33`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFunctionClone,
+	}
+	cmd.Flags().String("to-profile", "", "Profile of the org to copy the function to (required)")
+	cmd.Flags().String("version-id", "", "The ID of the version to copy")
+	cmd.Flags().String("select", "", output.SelectFlagUsage)
+	cmd.Flags().String("name", "", "Name of the new function. Default is the name of the source function")
+	cmd.Flags().StringArray("secret", nil, "Value of a secret of the source function, format: NAME=VALUE (can be used multiple times)")
+	cmd.Flags().Bool("deploy", false, "Also copy the deployment of the source version")
+	cmd.Flags().StringArray("backend-map", nil, "Backend to use in the target org instead of a source backend, format: SOURCE=TARGET (can be used multiple times)")
+	cmd.Flags().Bool("skip-validation", false, "With --deploy, copy the backends as they are without checking them against the target org's cluster groups")
+	cmd.Flags().BoolP("detached", "d", false, "Do not wait for the deployment")
+	addWaitFlags(cmd)
+	addInterruptFlag(cmd)
+	_ = cmd.MarkFlagRequired("to-profile")
+	return cmd
+}
+
+// cloneResult is what 'function clone' prints with --json
+type cloneResult struct {
+	SourceFunctionID string            `json:"sourceFunctionId"`
+	SourceVersionID  string            `json:"sourceVersionId"`
+	Profile          string            `json:"profile"`
+	OrgID            string            `json:"orgId"`
+	FunctionID       string            `json:"functionId"`
+	VersionID        string            `json:"versionId"`
+	ContainerImage   string            `json:"containerImage,omitempty"`
+	HelmChart        string            `json:"helmChart,omitempty"`
+	MissingSecrets   []string          `json:"missingSecrets,omitempty"`
+	Backends         map[string]string `json:"remappedBackends,omitempty"`
+	Deployed         bool              `json:"deployed"`
+}
+
+func runFunctionClone(cmd *cobra.Command, args []string) error {
+	functionID := args[0]
+	profileName, _ := cmd.Flags().GetString("to-profile")
+	profile, ok := config.GetProfile(profileName)
+	if !ok {
+		return output.Error(cmd, fmt.Sprintf("Profile %s not found. Save it with 'nvcf auth login --profile %s'", profileName, profileName), nil)
+	}
+	secrets, err := parseKeyValues(cmd, "secret")
+	if err != nil {
+		return err
+	}
+	backendMap, err := parseKeyValues(cmd, "backend-map")
+	if err != nil {
+		return err
+	}
+	deploy, _ := cmd.Flags().GetBool("deploy")
+
+	client := newClient(cmd)
+	target := newClientWithKey(cmd, profile.APIKey)
+
+	versionID, _ := cmd.Flags().GetString("version-id")
+	if versionID == "" {
+		versions, err := client.Functions.Versions.List(cmd.Context(), functionID)
+		if err != nil {
+			return output.Error(cmd, "Error listing function versions", err)
+		}
+		versionID, err = output.SelectVersion(cmd, versions.Functions, "clone")
+		if err != nil {
+			return err
+		}
+	}
+
+	source, err := client.Functions.Versions.Get(cmd.Context(), functionID, versionID, nvcf.FunctionVersionGetParams{
+		IncludeSecrets: nvcf.Bool(true),
+	})
+	if err != nil {
+		output.Info(cmd, "Could not read the version with its secrets; reading it without them")
+		source, err = client.Functions.Versions.Get(cmd.Context(), functionID, versionID, nvcf.FunctionVersionGetParams{
+			IncludeSecrets: nvcf.Bool(false),
+		})
+		if err != nil {
+			return output.Error(cmd, "Error getting function version", err)
+		}
+	}
+	fn := source.Function
+
+	result := cloneResult{
+		SourceFunctionID: functionID,
+		SourceVersionID:  versionID,
+		Profile:          profileName,
+		OrgID:            profile.OrgID,
+		ContainerImage:   rewriteRegistryOrg(fn.ContainerImage, config.GetOrgID(), profile.OrgID),
+		HelmChart:        rewriteRegistryOrg(fn.HelmChart, config.GetOrgID(), profile.OrgID),
+	}
+	if result.ContainerImage != fn.ContainerImage {
+		output.Info(cmd, fmt.Sprintf("Container image rewritten to %s", result.ContainerImage))
+	}
+	if result.HelmChart != fn.HelmChart {
+		output.Info(cmd, fmt.Sprintf("Helm chart rewritten to %s", result.HelmChart))
+	}
+
+	var secretParams []nvcf.FunctionNewParamsSecret
+	for _, name := range fn.Secrets {
+		value, ok := secrets[name]
+		if !ok {
+			result.MissingSecrets = append(result.MissingSecrets, name)
+			continue
+		}
+		secretParams = append(secretParams, nvcf.FunctionNewParamsSecret{Name: nvcf.F(name), Value: nvcf.F(value)})
+	}
+	if len(result.MissingSecrets) > 0 {
+		output.Info(cmd, fmt.Sprintf("Secrets not copied, pass their values with --secret NAME=VALUE: %s", strings.Join(result.MissingSecrets, ", ")))
+	}
+
+	// read and remap the deployment before creating anything, so that a
+	// deployment that cannot be copied leaves the target org untouched
+	var deploymentParams nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams
+	if deploy {
+		deploymentParams, result.Backends, err = cloneDeployment(cmd, client, functionID, versionID, profile, backendMap)
+		if err != nil {
+			return err
+		}
+	}
+
+	name, _ := cmd.Flags().GetString("name")
+	if name == "" {
+		name = fn.Name
+	}
+	params := cloneFunctionParams(fn, name, result.ContainerImage, result.HelmChart, secretParams)
+	created, err := target.Functions.New(cmd.Context(), params)
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Error creating the function in org %s", profile.OrgID), err)
+	}
+	result.FunctionID = created.Function.ID
+	result.VersionID = created.Function.VersionID
+	output.Success(cmd, fmt.Sprintf("Function %s created in org %s (ID: %s, Version: %s)", name, profile.OrgID, result.FunctionID, result.VersionID))
+
+	if deploy {
+		_, err = target.FunctionDeployment.Functions.Versions.InitiateDeployment(cmd.Context(), result.FunctionID, result.VersionID, deploymentParams)
+		if err != nil {
+			return output.Error(cmd, fmt.Sprintf("Error deploying the function in org %s", profile.OrgID), err)
+		}
+		result.Deployed = true
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return output.Error(cmd, "Error formatting JSON", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	}
+	if detached, _ := cmd.Flags().GetBool("detached"); deploy && !detached {
+		return WaitForDeployment(cmd, target, result.FunctionID, result.VersionID)
+	}
+	return nil
+}
+
+// cloneFunctionParams builds the request that recreates fn as a new function
+func cloneFunctionParams(fn nvcf.FunctionResponseFunction, name, containerImage, helmChart string, secrets []nvcf.FunctionNewParamsSecret) nvcf.FunctionNewParams {
+	params := nvcf.FunctionNewParams{
+		Name:          nvcf.String(name),
+		InferenceURL:  nvcf.String(fn.InferenceURL),
+		InferencePort: nvcf.Int(fn.InferencePort),
+		ContainerArgs: nvcf.String(fn.ContainerArgs),
+		APIBodyFormat: nvcf.F(nvcf.FunctionNewParamsAPIBodyFormat(fn.APIBodyFormat)),
+		Description:   nvcf.F(fn.Description),
+		Tags:          nvcf.F(fn.Tags),
+		FunctionType:  nvcf.F(nvcf.FunctionNewParamsFunctionType(fn.FunctionType)),
+		Health: nvcf.F(nvcf.FunctionNewParamsHealth{
+			Protocol:           nvcf.F(nvcf.FunctionNewParamsHealthProtocol(fn.Health.Protocol)),
+			Port:               nvcf.F(fn.Health.Port),
+			Timeout:            nvcf.F(fn.Health.Timeout),
+			ExpectedStatusCode: nvcf.F(fn.Health.ExpectedStatusCode),
+			Uri:                nvcf.String(fn.Health.Uri),
+		}),
+	}
+	if containerImage != "" {
+		params.ContainerImage = nvcf.String(containerImage)
+	}
+	if helmChart != "" {
+		params.HelmChart = nvcf.String(helmChart)
+		params.HelmChartServiceName = nvcf.String(fn.HelmChartServiceName)
+	}
+	var env []nvcf.FunctionNewParamsContainerEnvironment
+	for _, variable := range fn.ContainerEnvironment {
+		env = append(env, nvcf.FunctionNewParamsContainerEnvironment{Key: nvcf.F(variable.Key), Value: nvcf.F(variable.Value)})
+	}
+	if len(env) > 0 {
+		params.ContainerEnvironment = nvcf.F(env)
+	}
+	var models []nvcf.FunctionNewParamsModel
+	for _, model := range fn.Models {
+		models = append(models, nvcf.FunctionNewParamsModel{Name: nvcf.F(model.Name), Uri: nvcf.F(model.Uri), Version: nvcf.F(model.Version)})
+	}
+	if len(models) > 0 {
+		params.Models = nvcf.F(models)
+	}
+	var resources []nvcf.FunctionNewParamsResource
+	for _, resource := range fn.Resources {
+		resources = append(resources, nvcf.FunctionNewParamsResource{Name: nvcf.F(resource.Name), Uri: nvcf.F(resource.Uri), Version: nvcf.F(resource.Version)})
+	}
+	if len(resources) > 0 {
+		params.Resources = nvcf.F(resources)
+	}
+	if len(secrets) > 0 {
+		params.Secrets = nvcf.F(secrets)
+	}
+	return params
+}
+
+// cloneDeployment copies the deployment specifications of a version for the
+// org of profile, remapping the backends that org lacks. It returns the
+// remapped backends, source to target.
+func cloneDeployment(cmd *cobra.Command, client *api.Client, functionID, versionID string, profile config.Profile, backendMap map[string]string) (nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams, map[string]string, error) {
+	var params nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams
+	deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, versionID)
+	if err != nil {
+		return params, nil, output.Error(cmd, "Error reading the deployment of the source version. Is it deployed?", err)
+	}
+
+	var combinations []gpu.Combination
+	if skip, _ := cmd.Flags().GetBool("skip-validation"); !skip {
+		combinations, err = gpu.AvailableCombinationsForOrg(cmd.Context(), profile.APIKey, profile.OrgID)
+		if err != nil {
+			output.Info(cmd, "Could not read the target org's cluster groups; copying the backends as they are")
+		}
+	}
+
+	remapped := map[string]string{}
+	var specs []nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification
+	for _, spec := range deployment.Deployment.DeploymentSpecifications {
+		backend, mapped := backendMap[spec.Backend]
+		if !mapped {
+			backend = spec.Backend
+		}
+		if len(combinations) > 0 {
+			if mapped {
+				err = gpu.ValidateCombination(combinations, backend, spec.GPU, spec.InstanceType, spec.MaxInstances)
+			} else {
+				backend, err = targetBackend(combinations, backend, spec)
+			}
+			if err != nil {
+				return params, nil, output.Error(cmd, fmt.Sprintf("%v\nUse --backend-map SOURCE=TARGET to pick the backend, or --skip-validation to copy it as is.", err), nil)
+			}
+		}
+		if backend != spec.Backend {
+			remapped[spec.Backend] = backend
+			output.Info(cmd, fmt.Sprintf("Backend %s remapped to %s for %s %s", spec.Backend, backend, spec.GPU, spec.InstanceType))
+		}
+
+		param := nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification{
+			GPU:                   nvcf.String(spec.GPU),
+			InstanceType:          nvcf.String(spec.InstanceType),
+			Backend:               nvcf.String(backend),
+			MinInstances:          nvcf.Int(spec.MinInstances),
+			MaxInstances:          nvcf.Int(spec.MaxInstances),
+			MaxRequestConcurrency: nvcf.Int(spec.MaxRequestConcurrency),
+		}
+		if len(spec.Regions) > 0 {
+			param.Regions = nvcf.F(spec.Regions)
+		}
+		if len(spec.Attributes) > 0 {
+			param.Attributes = nvcf.F(spec.Attributes)
+		}
+		if spec.Configuration != nil {
+			param.Configuration = nvcf.F(spec.Configuration)
+		}
+		specs = append(specs, param)
+	}
+	params.DeploymentSpecifications = nvcf.F(specs)
+	if len(remapped) == 0 {
+		remapped = nil
+	}
+	return params, remapped, nil
+}
+
+// targetBackend returns backend if the target org offers the GPU and instance
+// type of spec on it, or else another backend of the target org that does
+func targetBackend(combinations []gpu.Combination, backend string, spec nvcf.DeploymentResponseDeploymentDeploymentSpecification) (string, error) {
+	var alternatives []string
+	for _, combination := range combinations {
+		if combination.GPU != spec.GPU || combination.InstanceType != spec.InstanceType {
+			continue
+		}
+		if combination.Backend == backend {
+			return backend, nil
+		}
+		alternatives = append(alternatives, combination.Backend)
+	}
+	if len(alternatives) == 0 {
+		return "", gpu.ValidateCombination(combinations, backend, spec.GPU, spec.InstanceType, spec.MaxInstances)
+	}
+	return alternatives[0], nil
+}
+
+// rewriteRegistryOrg moves an NGC registry path from one org to another, such
+// as nvcr.io/dev-org/team/image:1 to nvcr.io/prod-org/team/image:1
+func rewriteRegistryOrg(uri, fromOrg, toOrg string) string {
+	if fromOrg == "" || toOrg == "" || fromOrg == toOrg {
+		return uri
+	}
+	scheme := ""
+	rest := uri
+	if i := strings.Index(rest, "://"); i >= 0 {
+		scheme, rest = rest[:i+3], rest[i+3:]
+	}
+	for _, host := range registryHosts {
+		if strings.HasPrefix(rest, host+fromOrg+"/") {
+			return scheme + host + toOrg + "/" + strings.TrimPrefix(rest, host+fromOrg+"/")
+		}
+	}
+	return uri
+}
+
+// parseKeyValues reads a repeatable KEY=VALUE flag
+func parseKeyValues(cmd *cobra.Command, flag string) (map[string]string, error) {
+	values, _ := cmd.Flags().GetStringArray(flag)
+	parsed := map[string]string{}
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, output.Error(cmd, fmt.Sprintf("invalid --%s %q, expected KEY=VALUE", flag, value), nil)
+		}
+		parsed[key] = val
+	}
+	return parsed, nil
+}
@@ -5,20 +5,16 @@
 package function
 
 import (
-	"context"
 	"fmt"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/flagutil"
+	"github.com/brevdev/nvcf/functionspec"
 	"github.com/brevdev/nvcf/output"
-	"github.com/brevdev/nvcf/timeout"
 	"github.com/spf13/cobra"
 	"github.com/tmc/nvcf-go"
-	"gopkg.in/yaml.v3"
 )
 
 // default values for function creation
@@ -42,6 +38,7 @@ func functionCreateCmd() *cobra.Command {
 		streaming          bool //if false this sets functionType to DEFAULT
 		functionType       string
 		envVars            []string
+		envFile            string
 		modelVars          []string
 		existingFunctionID string
 
@@ -63,18 +60,31 @@ func functionCreateCmd() *cobra.Command {
 
 		// Optional function specification file
 		fileSpec string
+		overlays []string
+		specVars []string
+		runOpts  specRunOptions
 	)
 
 	cmd := &cobra.Command{
 		Use:   "create",
 		Short: "Create a new function",
-		Long:  `Create a new NVCF Function with the specified parameters. If you specify --from-version, we will create a new version of an existing function. You can also create and deploy a function in one step using the --deploy flag.`,
+		Long:  `Create a new NVCF Function with the specified parameters. If you specify --from-version, we will create a new version of an existing function. You can also create and deploy a function in one step using the --deploy flag; in a terminal, leaving out --backend, --gpu or --instance-type opens a picker of the combinations available to your org.`,
 		Example: `Create a new function:
 nvcf function create --name myfunction --inference-url /v1/chat/completions --inference-port 80 --health-uri /health --container-image nvcr.io/nvidia/example-image:latest
    
 Create and deploy a new function from a file:
 nvcf function create --file deploy.yaml --deploy
 
+Create and deploy up to 8 functions from a file at a time, then retry the ones that failed:
+nvcf function create --file deploy.yaml --deploy --parallel 8
+nvcf function create --file deploy.yaml --deploy --retry-failed deploy.results.json
+
+Create and deploy functions from a file, stopping the deployments still in progress on Ctrl-C:
+nvcf function create --file deploy.yaml --deploy --on-interrupt cancel
+
+Create functions from a base file with a production overlay:
+nvcf function create --file base.yaml --overlay prod.yaml --var IMAGE_TAG=1.2.0
+
 Create a new version of an existing function:
 nvcf function create --from-version existing-function-id --name newversion --inference-url /v2/chat/completions --inference-port 8080 --health-uri /healthcheck --container-image nvcr.io/nvidia/updated-image:v2
 `,
@@ -83,8 +93,8 @@ nvcf function create --from-version existing-function-id --name newversion --inf
 			deploy, _ := cmd.Flags().GetBool("deploy")
 			if fileSpec == "" {
 				requiredFlags := []string{"name", "inference-url", "inference-port", "health-uri", "container-image"}
-				if deploy {
-					requiredFlags = append(requiredFlags, "min-instances", "max-instances", "gpu", "instance-type", "backend", "max-request-concurrency")
+				if deploy && !output.CanPrompt(cmd) {
+					requiredFlags = append(requiredFlags, deploymentFlags...)
 				}
 				for _, flag := range requiredFlags {
 					if err := cmd.MarkFlagRequired(flag); err != nil {
@@ -94,10 +104,36 @@ nvcf function create --from-version existing-function-id --name newversion --inf
 			}
 			return nil
 		}, RunE: func(cmd *cobra.Command, args []string) error {
-			client := api.NewClient(config.GetAPIKey())
+			client := newClient(cmd)
 
 			if fileSpec != "" {
-				return createFunctionsFromFile(cmd, client, fileSpec, deploy)
+				vars, err := functionspec.ParseVars(specVars)
+				if err != nil {
+					return output.Error(cmd, err.Error(), nil)
+				}
+				opts := functionspec.Options{Deploy: deploy, Vars: vars, Overlays: overlays}
+				runOpts.Detached = detatched
+				runOpts.Wait = waitOptionsFromFlags(cmd)
+				runOpts.OnInterrupt, _ = cmd.Flags().GetString("on-interrupt")
+				if runOpts.Atomic && cmd.Flags().Changed("on-interrupt") {
+					return output.Error(cmd, "--on-interrupt cannot be used with --atomic, which rolls back an interrupted run", nil)
+				}
+				return createFunctionsFromFile(cmd, client, fileSpec, opts, runOpts)
+			}
+
+			if deploy && wantsDeployWizard(cmd) {
+				if err := runDeployWizard(cmd, args); err != nil {
+					return err
+				}
+			}
+
+			if deploy {
+				if err := validateDeployment(cmd, backend, gpu, instanceType, maxInstances); err != nil {
+					return err
+				}
+				if err := checkHourlyBudget(cmd, budgetedDeployment{GPU: gpu, InstanceType: instanceType, MaxInstances: maxInstances}); err != nil {
+					return err
+				}
 			}
 
 			if existingFunctionID != "" {
@@ -118,9 +154,9 @@ nvcf function create --from-version existing-function-id --name newversion --inf
 			}
 
 			if existingFunctionID != "" {
-				containerEnv, err := parseEnvVarsNewVersion(cmd, envVars)
+				containerEnv, err := parseEnvVarsNewVersion(cmd, envFile, envVars)
 				if err != nil {
-					return output.Error(cmd, "error parsing environment variables", err)
+					return err
 				}
 				models, err := parseModelsNewVersion(cmd, modelVars)
 				if err != nil {
@@ -158,9 +194,9 @@ nvcf function create --from-version existing-function-id --name newversion --inf
 					return deployFunction(cmd, client, resp, gpu, instanceType, backend, maxInstances, minInstances, maxRequestConcurrency)
 				}
 			} else {
-				containerEnv, err := parseEnvVars(cmd, envVars)
+				containerEnv, err := parseEnvVars(cmd, envFile, envVars)
 				if err != nil {
-					return output.Error(cmd, "error parsing environment variables", err)
+					return err
 				}
 				models, err := parseModels(cmd, modelVars)
 				if err != nil {
@@ -213,7 +249,8 @@ nvcf function create --from-version existing-function-id --name newversion --inf
 	cmd.Flags().StringSliceVar(&tags, "tag", nil, "Tags for the function (can be used multiple times)")
 	cmd.Flags().BoolVar(&streaming, "streaming", true, "Set function type to STREAMING. Default is true")
 	cmd.Flags().StringVar(&functionType, "function-type", defaultFunctionType, "Function type (DEFAULT or STREAMING). Default is DEFAULT")
-	cmd.Flags().StringSliceVar(&envVars, "env", []string{}, "Environment variables for the function (can be used multiple times, format: key:value)")
+	cmd.Flags().StringSliceVar(&envVars, "env", []string{}, "Environment variables for the function (can be used multiple times, format: KEY=VALUE, key:value, or KEY to pass through the local value)")
+	cmd.Flags().StringVar(&envFile, "env-file", "", "Dotenv file with environment variables for the function. --env values override it")
 	cmd.Flags().StringSliceVar(&modelVars, "model", []string{}, "Models for the function (can be used multiple times, format: name:uri:version)")
 
 	//optional new version flag
@@ -233,43 +270,53 @@ nvcf function create --from-version existing-function-id --name newversion --inf
 	cmd.Flags().StringVar(&backend, "backend", "", "Backend to deploy the function to (see your NGC org available backends)")
 	cmd.Flags().Int64Var(&maxRequestConcurrency, "max-request-concurrency", 1, "Maximum number of concurrent requests. Default is 1")
 	cmd.Flags().BoolVar(&deploy, "deploy", false, "Create and deploy the function in one step. Default is false")
+	cmd.Flags().Bool("skip-validation", false, "With --deploy, skip checking the GPU, instance type, backend and max instances against the org's cluster groups")
+	addBudgetFlag(cmd)
 	cmd.Flags().StringVarP(&fileSpec, "file", "f", "", "Path to a YAML file containing function specifications")
+	cmd.Flags().StringSliceVar(&overlays, "overlay", nil, "Spec file to deep-merge over the --file spec (can be used multiple times)")
+	cmd.Flags().StringArrayVar(&specVars, "var", nil, "Variable for ${VAR} references in the --file spec, format: KEY=VALUE (can be used multiple times)")
+	cmd.Flags().IntVar(&runOpts.Parallel, "parallel", 4, "Number of functions from --file to create and deploy at the same time")
+	cmd.Flags().StringVar(&runOpts.ResultsFile, "results-file", "", "Where to write the JSON results of a --file run. Default is <file>.results.json")
+	cmd.Flags().BoolVar(&runOpts.Atomic, "atomic", false, "If any function from --file fails, stop the deployments and delete the functions and versions created by the run")
+	cmd.Flags().StringVar(&runOpts.RetryFailed, "retry-failed", "", "Results file of a previous --file run; only the functions that failed, or that it has no result for, are created and deployed again")
 	cmd.Flags().BoolVarP(&detatched, "detatched", "d", false, "Deploy the function in the background. Default is false")
+	addWaitFlags(cmd)
+	addInterruptFlag(cmd)
 	return cmd
 }
 
-func parseEnvVars(cmd *cobra.Command, envVars []string) ([]nvcf.FunctionNewParamsContainerEnvironment, error) {
-	var containerEnv []nvcf.FunctionNewParamsContainerEnvironment
-
-	for _, env := range envVars {
-		parts := strings.SplitN(env, ":", 2)
-		if len(parts) != 2 {
-			return nil, output.Error(cmd, fmt.Sprintf("invalid environment variable format: %s. ensure that you are using the format key:value", env), nil)
-		}
-		containerEnv = append(containerEnv, nvcf.FunctionNewParamsContainerEnvironment{
-			Key:   nvcf.F(parts[0]),
-			Value: nvcf.F(parts[1]),
-		})
+func parseEnvVars(cmd *cobra.Command, envFile string, envVars []string) ([]nvcf.FunctionNewParamsContainerEnvironment, error) {
+	env, err := readEnvFlags(cmd, envFile, envVars)
+	if err != nil {
+		return nil, err
 	}
-
-	return containerEnv, nil
+	return parseEnvVarsFromFile(env), nil
 }
 
-func parseEnvVarsNewVersion(cmd *cobra.Command, envVars []string) ([]nvcf.FunctionVersionNewParamsContainerEnvironment, error) {
-	var containerEnv []nvcf.FunctionVersionNewParamsContainerEnvironment
+func parseEnvVarsNewVersion(cmd *cobra.Command, envFile string, envVars []string) ([]nvcf.FunctionVersionNewParamsContainerEnvironment, error) {
+	env, err := readEnvFlags(cmd, envFile, envVars)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvVarsFromFileNewVersion(env), nil
+}
 
-	for _, env := range envVars {
-		parts := strings.SplitN(env, ":", 2)
-		if len(parts) != 2 {
-			return nil, output.Error(cmd, fmt.Sprintf("invalid environment variable format: %s. ensure that you are using the format key:value", env), nil)
+// readEnvFlags combines the --env-file and --env values, --env taking precedence
+func readEnvFlags(cmd *cobra.Command, envFile string, envVars []string) ([]functionspec.EnvVar, error) {
+	var fromFile []functionspec.EnvVar
+	if envFile != "" {
+		env, err := functionspec.ReadEnvFile(envFile)
+		if err != nil {
+			// the parse error names the file and line
+			return nil, output.Error(cmd, fmt.Sprintf("error reading env file: %v", err), nil)
 		}
-		containerEnv = append(containerEnv, nvcf.FunctionVersionNewParamsContainerEnvironment{
-			Key:   nvcf.F(parts[0]),
-			Value: nvcf.F(parts[1]),
-		})
+		fromFile = env
 	}
-
-	return containerEnv, nil
+	fromFlags, err := functionspec.ParseEnv(envVars)
+	if err != nil {
+		return nil, output.Error(cmd, err.Error(), nil)
+	}
+	return functionspec.MergeEnv(fromFile, fromFlags), nil
 }
 
 func parseModels(cmd *cobra.Command, modelVars []string) ([]nvcf.FunctionNewParamsModel, error) {
@@ -311,17 +358,7 @@ func deployFunction(cmd *cobra.Command, client *api.Client, resp *nvcf.CreateFun
 	maxInstances, minInstances, maxRequestConcurrency int64) error {
 	output.Info(cmd, "Deployment flag was provided. Deploying function...")
 
-	deploymentParams := nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams{
-		DeploymentSpecifications: nvcf.F([]nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification{{
-			GPU:                   nvcf.String(gpu),
-			InstanceType:          nvcf.String(instanceType),
-			Backend:               nvcf.String(backend),
-			MaxInstances:          nvcf.Int(maxInstances),
-			MinInstances:          nvcf.Int(minInstances),
-			MaxRequestConcurrency: nvcf.Int(maxRequestConcurrency),
-			// missing attributes, availabilityZones, clusters, configuration, preferredOrder, regions
-		}}),
-	}
+	deploymentParams := newDeploymentParams(gpu, instanceType, backend, maxInstances, minInstances, maxRequestConcurrency)
 
 	deployResp, err := client.FunctionDeployment.Functions.Versions.InitiateDeployment(
 		cmd.Context(),
@@ -343,56 +380,45 @@ func deployFunction(cmd *cobra.Command, client *api.Client, resp *nvcf.CreateFun
 	return nil
 }
 
-func createFunctionsFromFile(cmd *cobra.Command, client *api.Client, yamlFile string, deploy bool) error {
-	data, err := os.ReadFile(yamlFile)
-	if err != nil {
-		return output.Error(cmd, "error reading YAML file", err)
-	}
-
-	var spec FunctionSpec
-	if err := yaml.Unmarshal(data, &spec); err != nil {
-		return output.Error(cmd, "error parsing YAML file", err)
-	}
-
-	for _, fn := range spec.Functions {
-		if fn.ExistingFunctionID != "" {
-			params := prepareFunctionVersionParamsFromFile(spec.FnImage, fn)
-			if err := createAndDeployFunctionVersionFromFile(cmd, client, fn.ExistingFunctionID, params, deploy, fn.InstGPUType, fn.InstType, fn.InstBackend, fn.InstMax, fn.InstMin, fn.InstMaxRequestConcurrency); err != nil {
-				return err
-			}
-		} else {
-			params := prepareFunctionParamsFromFile(spec.FnImage, fn)
-			if err := createAndDeployFunctionFromFile(cmd, client, params, deploy, fn.InstGPUType, fn.InstType, fn.InstBackend, fn.InstMax, fn.InstMin, fn.InstMaxRequestConcurrency); err != nil {
-				return err
-			}
-		}
+// newDeploymentParams builds the request to deploy a function version with a single deployment specification
+func newDeploymentParams(gpu, instanceType, backend string, maxInstances, minInstances, maxRequestConcurrency int64) nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams {
+	return nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams{
+		DeploymentSpecifications: nvcf.F([]nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification{{
+			GPU:                   nvcf.String(gpu),
+			InstanceType:          nvcf.String(instanceType),
+			Backend:               nvcf.String(backend),
+			MaxInstances:          nvcf.Int(maxInstances),
+			MinInstances:          nvcf.Int(minInstances),
+			MaxRequestConcurrency: nvcf.Int(maxRequestConcurrency),
+			// missing attributes, availabilityZones, clusters, configuration, preferredOrder, regions
+		}}),
 	}
-
-	return nil
 }
 
-func prepareFunctionVersionParamsFromFile(fnImage string, fn FunctionDef) nvcf.FunctionVersionNewParams {
-	apiBodyFormat := defaultAPIBodyFormat
-	if !fn.Custom {
-		apiBodyFormat = "PREDICT_V2"
+// prepareFunctionVersionParamsFromFile builds the new version request for a function whose defaults have been applied
+func prepareFunctionVersionParamsFromFile(fn functionspec.FunctionDef) nvcf.FunctionVersionNewParams {
+	apiBodyFormat := "PREDICT_V2"
+	if fn.Custom != nil && *fn.Custom {
+		apiBodyFormat = "CUSTOM"
 	}
 
-	functionType := defaultFunctionType
-	if !fn.Streaming {
-		functionType = "DEFAULT"
+	functionType := "DEFAULT"
+	if fn.Streaming != nil && *fn.Streaming {
+		functionType = "STREAMING"
 	}
 
 	return nvcf.FunctionVersionNewParams{
 		Name:                 nvcf.String(fn.FnName),
 		InferenceURL:         nvcf.String(fn.InferenceURL),
 		InferencePort:        nvcf.Int(fn.InferencePort),
-		ContainerImage:       nvcf.String(fnImage),
+		ContainerImage:       nvcf.String(fn.ContainerImage),
 		ContainerEnvironment: nvcf.F(parseEnvVarsFromFileNewVersion(fn.ContainerEnvironment)),
 		ContainerArgs:        nvcf.String(fn.ContainerArgs),
 		APIBodyFormat:        nvcf.F(nvcf.FunctionVersionNewParamsAPIBodyFormat(apiBodyFormat)),
 		Description:          nvcf.F(fn.Description),
 		Tags:                 nvcf.F(fn.Tags),
 		FunctionType:         nvcf.F(nvcf.FunctionVersionNewParamsFunctionType(functionType)),
+		Models:               nvcf.F(parseModelsFromFileNewVersion(fn.Models)),
 		Health: nvcf.F(nvcf.FunctionVersionNewParamsHealth{
 			Protocol:           nvcf.F(nvcf.FunctionVersionNewParamsHealthProtocol(fn.Health.Protocol)),
 			Port:               nvcf.F(fn.Health.Port),
@@ -403,7 +429,7 @@ func prepareFunctionVersionParamsFromFile(fnImage string, fn FunctionDef) nvcf.F
 	}
 }
 
-func parseEnvVarsFromFileNewVersion(envVars []EnvVar) []nvcf.FunctionVersionNewParamsContainerEnvironment {
+func parseEnvVarsFromFileNewVersion(envVars []functionspec.EnvVar) []nvcf.FunctionVersionNewParamsContainerEnvironment {
 	var containerEnv []nvcf.FunctionVersionNewParamsContainerEnvironment
 
 	for _, env := range envVars {
@@ -416,7 +442,7 @@ func parseEnvVarsFromFileNewVersion(envVars []EnvVar) []nvcf.FunctionVersionNewP
 	return containerEnv
 }
 
-func parseEnvVarsFromFile(envVars []EnvVar) []nvcf.FunctionNewParamsContainerEnvironment {
+func parseEnvVarsFromFile(envVars []functionspec.EnvVar) []nvcf.FunctionNewParamsContainerEnvironment {
 	var containerEnv []nvcf.FunctionNewParamsContainerEnvironment
 
 	for _, env := range envVars {
@@ -429,43 +455,58 @@ func parseEnvVarsFromFile(envVars []EnvVar) []nvcf.FunctionNewParamsContainerEnv
 	return containerEnv
 }
 
-func createAndDeployFunctionVersionFromFile(cmd *cobra.Command, client *api.Client, existingFunctionID string, params nvcf.FunctionVersionNewParams, deploy bool, gpu, instanceType, backend string, maxInstances, minInstances, maxRequestConcurrency int64) error {
-	resp, err := client.Functions.Versions.New(cmd.Context(), existingFunctionID, params)
-	if err != nil {
-		return output.Error(cmd, "error creating function version", err)
+func parseModelsFromFileNewVersion(modelDefs []functionspec.ModelDef) []nvcf.FunctionVersionNewParamsModel {
+	var models []nvcf.FunctionVersionNewParamsModel
+
+	for _, model := range modelDefs {
+		models = append(models, nvcf.FunctionVersionNewParamsModel{
+			Name:    nvcf.F(model.Name),
+			Uri:     nvcf.F(model.Uri),
+			Version: nvcf.F(model.Version),
+		})
 	}
 
-	output.Success(cmd, fmt.Sprintf("Version %s created for function %s", resp.Function.VersionID, existingFunctionID))
+	return models
+}
+
+func parseModelsFromFile(modelDefs []functionspec.ModelDef) []nvcf.FunctionNewParamsModel {
+	var models []nvcf.FunctionNewParamsModel
 
-	if deploy {
-		return deployFunction(cmd, client, resp, gpu, instanceType, backend, maxInstances, minInstances, maxRequestConcurrency)
+	for _, model := range modelDefs {
+		models = append(models, nvcf.FunctionNewParamsModel{
+			Name:    nvcf.F(model.Name),
+			Uri:     nvcf.F(model.Uri),
+			Version: nvcf.F(model.Version),
+		})
 	}
 
-	return nil
+	return models
 }
 
-func prepareFunctionParamsFromFile(fnImage string, fn FunctionDef) nvcf.FunctionNewParams {
-	apiBodyFormat := defaultAPIBodyFormat
-	if !fn.Custom {
-		apiBodyFormat = "PREDICT_V2"
+// prepareFunctionParamsFromFile builds the new function request for a function whose defaults have been applied
+func prepareFunctionParamsFromFile(fn functionspec.FunctionDef) nvcf.FunctionNewParams {
+	apiBodyFormat := "PREDICT_V2"
+	if fn.Custom != nil && *fn.Custom {
+		apiBodyFormat = "CUSTOM"
 	}
 
-	functionType := defaultFunctionType
-	if !fn.Streaming {
-		functionType = "DEFAULT"
+	functionType := "DEFAULT"
+	if fn.Streaming != nil && *fn.Streaming {
+		functionType = "STREAMING"
 	}
 
 	return nvcf.FunctionNewParams{
 		Name:                 nvcf.String(fn.FnName),
 		InferenceURL:         nvcf.String(fn.InferenceURL),
 		InferencePort:        nvcf.Int(fn.InferencePort),
-		ContainerImage:       nvcf.String(fnImage),
+		ContainerImage:       nvcf.String(fn.ContainerImage),
 		ContainerArgs:        nvcf.String(fn.ContainerArgs),
 		APIBodyFormat:        nvcf.F(nvcf.FunctionNewParamsAPIBodyFormat(apiBodyFormat)),
 		Description:          nvcf.F(fn.Description),
 		Tags:                 nvcf.F(fn.Tags),
 		FunctionType:         nvcf.F(nvcf.FunctionNewParamsFunctionType(functionType)),
 		ContainerEnvironment: nvcf.F(parseEnvVarsFromFile(fn.ContainerEnvironment)),
+		Models:               nvcf.F(parseModelsFromFile(fn.Models)),
 		Health: nvcf.F(nvcf.FunctionNewParamsHealth{
 			Protocol:           nvcf.F(nvcf.FunctionNewParamsHealthProtocol(fn.Health.Protocol)),
 			Port:               nvcf.F(fn.Health.Port),
@@ -475,52 +516,3 @@ func prepareFunctionParamsFromFile(fnImage string, fn FunctionDef) nvcf.Function
 		}),
 	}
 }
-
-func createAndDeployFunctionFromFile(cmd *cobra.Command, client *api.Client, params nvcf.FunctionNewParams, deploy bool, gpu, instanceType, backend string, maxInstances, minInstances, maxRequestConcurrency int64) error {
-	resp, err := client.Functions.New(cmd.Context(), params)
-	if err != nil {
-		return output.Error(cmd, "error creating function", err)
-	}
-
-	if deploy {
-		return deployFunction(cmd, client, resp, gpu, instanceType, backend, maxInstances, minInstances, maxRequestConcurrency)
-	}
-
-	output.Success(cmd, fmt.Sprintf("Function %s created (ID: %s, Version: %s)", params.Name, resp.Function.ID, resp.Function.VersionID))
-	return nil
-}
-
-func WaitForDeployment(cmd *cobra.Command, client *api.Client, functionID, versionID string) error {
-	spinner := output.NewSpinner("Waiting for deployment to complete...")
-	output.StartSpinner(spinner)
-
-	err := timeout.DoWithTimeout(func(ctx context.Context) error {
-		for ctx.Err() == nil {
-			deploymentStatus, err := client.FunctionDeployment.Functions.Versions.GetDeployment(
-				ctx,
-				functionID,
-				versionID,
-			)
-			if err != nil {
-				return err
-			}
-			if deploymentStatus.Deployment.FunctionStatus == nvcf.DeploymentResponseDeploymentFunctionStatusActive {
-				return nil
-			}
-			if deploymentStatus.Deployment.FunctionStatus == nvcf.DeploymentResponseDeploymentFunctionStatusError {
-				return fmt.Errorf("deployment failed. please try again")
-			}
-			time.Sleep(time.Second * 5)
-		}
-		return nil
-	}, 30*time.Minute) // GFN can take a while
-
-	if err != nil {
-		return output.Error(cmd, "Error waiting for deployment", err)
-	}
-
-	output.StopSpinner(spinner)
-
-	output.Success(cmd, fmt.Sprintf("\nFunction deployed (ID: %s, Version: %s)", functionID, versionID))
-	return nil
-}
@@ -0,0 +1,129 @@
+package function
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+// kinds of resources recorded by a specJournal
+const (
+	resourceFunction   = "function"
+	resourceVersion    = "version"
+	resourceDeployment = "deployment"
+)
+
+const (
+	// rollbackTimeout bounds a whole rollback, which runs even after the command was interrupted
+	rollbackTimeout = 30 * time.Minute
+	// rollbackStopTimeout is how long a rollback waits for the instances of a stopped deployment to be gone
+	rollbackStopTimeout = 10 * time.Minute
+)
+
+// specJournal records every resource created during an --atomic spec run, in
+// the order they were created, so that they can be undone if the run fails.
+type specJournal struct {
+	mu        sync.Mutex
+	resources []createdResource
+}
+
+type createdResource struct {
+	Kind       string
+	Name       string
+	FunctionID string
+	VersionID  string
+}
+
+// rollbackResult is the outcome of undoing one created resource
+type rollbackResult struct {
+	createdResource
+	Err error
+}
+
+func (j *specJournal) record(kind, name, functionID, versionID string) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.resources = append(j.resources, createdResource{Kind: kind, Name: name, FunctionID: functionID, VersionID: versionID})
+}
+
+// rollback stops the deployments and deletes the functions and versions in the
+// journal in reverse order. Every resource is attempted even if an earlier one fails.
+func (j *specJournal) rollback(ctx context.Context, client *api.Client) []rollbackResult {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	results := make([]rollbackResult, 0, len(j.resources))
+	for i := len(j.resources) - 1; i >= 0; i-- {
+		resource := j.resources[i]
+		var err error
+		switch resource.Kind {
+		case resourceDeployment:
+			// the version can only be deleted once its instances are gone
+			err = stopDeployment(ctx, client, resource.FunctionID, resource.VersionID, stopOptions{
+				Wait:        true,
+				waitOptions: waitOptions{Timeout: rollbackStopTimeout, PollInterval: defaultPollInterval},
+			}, nil)
+		case resourceFunction, resourceVersion:
+			// deleting the only version of a new function deletes the function
+			err = client.Functions.Versions.Delete(ctx, resource.FunctionID, resource.VersionID)
+		}
+		results = append(results, rollbackResult{createdResource: resource, Err: err})
+	}
+	return results
+}
+
+// printRollbackReport lists what was undone and returns the resources that could not be undone
+func printRollbackReport(cmd *cobra.Command, results []rollbackResult) []rollbackResult {
+	var failed []rollbackResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	if len(results) == 0 {
+		output.Info(cmd, "Rollback: nothing was created, nothing to undo")
+		return nil
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		return failed
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "\nRollback report:")
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Resource", "Name", "Function ID", "Version ID", "Result"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, result := range results {
+		outcome := "stopped"
+		if result.Kind != resourceDeployment {
+			outcome = "deleted"
+		}
+		if result.Err != nil {
+			outcome = fmt.Sprintf("NOT UNDONE: %v", result.Err)
+		}
+		table.Append([]string{result.Kind, result.Name, result.FunctionID, result.VersionID, outcome})
+	}
+	table.Render()
+
+	if len(failed) > 0 {
+		fmt.Fprintln(cmd.OutOrStdout(), "\nThe following resources could not be undone and must be cleaned up by hand:")
+		for _, result := range failed {
+			switch result.Kind {
+			case resourceDeployment:
+				fmt.Fprintf(cmd.OutOrStdout(), "  nvcf function stop %s --version-id %s\n", result.FunctionID, result.VersionID)
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "  nvcf function delete %s --version-id %s\n", result.FunctionID, result.VersionID)
+			}
+		}
+	}
+	return failed
+}
@@ -0,0 +1,461 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/collections"
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/brevdev/nvcf/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// statuses of a function in a spec run
+const (
+	specStatusCreated   = "created"
+	specStatusDeploying = "deploying"
+	specStatusDeployed  = "deployed"
+	specStatusFailed    = "failed"
+	specStatusSkipped   = "skipped"
+	specStatusReverted  = "rolled back"
+)
+
+// stages of a function in a spec run, recorded for failed entries
+const (
+	specStageCreate = "create"
+	specStageDeploy = "deploy"
+)
+
+// specRunOptions control how the functions of a spec file are created
+type specRunOptions struct {
+	Parallel    int
+	ResultsFile string
+	RetryFailed string
+	Detached    bool
+	Atomic      bool
+	// OnInterrupt is what an interrupt does to the deployments being waited for, as
+	// set by --on-interrupt. --atomic rolls them back instead.
+	OnInterrupt string
+	Wait        waitOptions
+}
+
+// specResults is the machine-readable record of a spec run
+type specResults struct {
+	File      string       `json:"file"`
+	StartedAt time.Time    `json:"startedAt"`
+	Results   []specResult `json:"results"`
+}
+
+// specResult is the outcome for one function of the spec
+type specResult struct {
+	// Index is the position of the function in the spec
+	Index      int    `json:"index"`
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Stage      string `json:"stage,omitempty"`
+	FunctionID string `json:"functionId,omitempty"`
+	VersionID  string `json:"versionId,omitempty"`
+	Error      string `json:"error,omitempty"`
+
+	err error
+}
+
+func createFunctionsFromFile(cmd *cobra.Command, client *api.Client, yamlFile string, opts functionspec.Options, runOpts specRunOptions) error {
+	if runOpts.OnInterrupt == "" {
+		runOpts.OnInterrupt = interruptAsk
+	}
+	if !collections.ListContains(interruptActions, runOpts.OnInterrupt) {
+		return output.Error(cmd, fmt.Sprintf("Invalid --on-interrupt %q, expected one of %s", runOpts.OnInterrupt, strings.Join(interruptActions, ", ")), nil)
+	}
+	spec, err := functionspec.Load(yamlFile, opts)
+	if err != nil {
+		var validationErrs functionspec.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return output.Error(cmd, fmt.Sprintf("invalid function specification:\n%s", validationErrs), nil)
+		}
+		return output.Error(cmd, "error reading YAML file", err)
+	}
+	spec.ApplyDefaults()
+	if opts.Deploy {
+		if err := validateSpecDeployments(cmd, spec.Functions); err != nil {
+			return err
+		}
+		var deployments []budgetedDeployment
+		for _, fn := range spec.Functions {
+			deployments = append(deployments, budgetedDeployment{Name: fn.FnName, GPU: fn.InstGPUType, InstanceType: fn.InstType, MaxInstances: fn.InstMax})
+		}
+		if err := checkHourlyBudget(cmd, deployments...); err != nil {
+			return err
+		}
+	}
+
+	results := &specResults{File: yamlFile, StartedAt: time.Now().UTC()}
+	functions := spec.Functions
+	indexes := make([]int, len(functions))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	previous := make([]*specResult, len(functions))
+	if runOpts.RetryFailed != "" {
+		retry, err := readSpecResults(runOpts.RetryFailed)
+		if err != nil {
+			return output.Error(cmd, fmt.Sprintf("error reading results file %s", runOpts.RetryFailed), err)
+		}
+		indexes, previous, results.Results = failedFunctions(spec.Functions, retry)
+		functions = make([]functionspec.FunctionDef, len(indexes))
+		for i, index := range indexes {
+			functions[i] = spec.Functions[index]
+		}
+		if len(functions) == 0 {
+			output.Success(cmd, fmt.Sprintf("No failed functions to retry in %s", runOpts.RetryFailed))
+			return nil
+		}
+	}
+	if runOpts.ResultsFile == "" {
+		runOpts.ResultsFile = defaultResultsFile(yamlFile)
+	}
+
+	labels := make([]string, len(functions))
+	for i, fn := range functions {
+		labels[i] = fn.FnName
+	}
+	progress := output.NewProgress(cmd, labels)
+	progress.Start()
+
+	// an interrupt cancels the functions that have not finished; in --atomic mode the
+	// first failure does too
+	runCtx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(runCtx)
+	defer cancel()
+	var journal *specJournal
+	if runOpts.Atomic {
+		journal = &specJournal{}
+	}
+
+	runResults := make([]specResult, len(functions))
+	sem := make(chan struct{}, max(runOpts.Parallel, 1))
+	var wg sync.WaitGroup
+	for i, fn := range functions {
+		wg.Add(1)
+		go func(i int, fn functionspec.FunctionDef) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				runResults[i] = specResult{Index: indexes[i], Name: fn.FnName, Status: specStatusSkipped}
+				progress.Skip(i, specStatusSkipped)
+				return
+			}
+			runResults[i] = runSpecFunction(ctx, client, fn, previous[i], journal, opts.Deploy, runOpts.Detached, runOpts.Wait, func(status string) {
+				progress.Update(i, status)
+			})
+			runResults[i].Index = indexes[i]
+			switch {
+			case runResults[i].Status != specStatusFailed:
+				progress.Finish(i, runResults[i].Status, nil)
+			case runOpts.Atomic && errors.Is(runResults[i].err, context.Canceled) && runCtx.Err() == nil:
+				runResults[i].Status, runResults[i].Stage, runResults[i].Error = specStatusSkipped, "", "canceled after another function failed"
+				progress.Skip(i, specStatusSkipped)
+			case !runOpts.Atomic && errors.Is(runResults[i].err, context.Canceled) && runCtx.Err() != nil:
+				// the deployment keeps running until the interrupt is handled
+				runResults[i].Error = "interrupted"
+				if runResults[i].Stage == specStageDeploy && runResults[i].VersionID != "" {
+					runResults[i].Status, runResults[i].Stage = specStatusDeploying, ""
+				}
+				progress.Skip(i, "interrupted")
+			default:
+				progress.Finish(i, runResults[i].Status, runResults[i].err)
+				if runOpts.Atomic {
+					cancel()
+				}
+			}
+		}(i, fn)
+	}
+	wg.Wait()
+	progress.Stop()
+	interrupted := runCtx.Err() != nil
+	// a second interrupt exits right away
+	stop()
+
+	failed := 0
+	for _, result := range runResults {
+		if result.Status == specStatusFailed {
+			failed++
+		}
+	}
+	var rollback []rollbackResult
+	if runOpts.Atomic && (failed > 0 || interrupted) {
+		if interrupted {
+			output.Info(cmd, "Interrupted")
+		}
+		output.Info(cmd, "Rolling back the resources created by this run...")
+		// the run's context is canceled after an interrupt, but the rollback must still run
+		rollbackCtx, cancelRollback := context.WithTimeout(context.Background(), rollbackTimeout)
+		rollback = journal.rollback(rollbackCtx, client)
+		cancelRollback()
+		markRolledBack(runResults, rollback)
+	}
+
+	if interrupted && !runOpts.Atomic {
+		output.Info(cmd, "Interrupted")
+		handleSpecInterrupt(cmd, client, runResults, runOpts.OnInterrupt)
+	}
+
+	results.Results = append(results.Results, runResults...)
+	sortResultsBySpec(results.Results)
+	// a dry run's placeholder IDs must not end up in a file used for retries
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		runOpts.ResultsFile = ""
+	} else if err := writeSpecResults(runOpts.ResultsFile, results); err != nil {
+		return output.Error(cmd, fmt.Sprintf("error writing results file %s", runOpts.ResultsFile), err)
+	}
+	printSpecSummary(cmd, results, runOpts.ResultsFile)
+
+	if runOpts.Atomic && (failed > 0 || interrupted) {
+		outcome := fmt.Sprintf("%d of %d function(s) failed", failed, len(runResults))
+		if interrupted {
+			outcome = "the run was interrupted"
+		}
+		if notUndone := printRollbackReport(cmd, rollback); len(notUndone) > 0 {
+			return fmt.Errorf("%s and %d resource(s) could not be rolled back", outcome, len(notUndone))
+		}
+		return fmt.Errorf("%s; every resource created by this run was rolled back", outcome)
+	}
+	if interrupted {
+		return fmt.Errorf("the run was interrupted. Run the functions that did not finish again with 'nvcf function create -f %s --retry-failed %s'", yamlFile, runOpts.ResultsFile)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d function(s) failed. Retry them with 'nvcf function create -f %s --retry-failed %s'", failed, len(runResults), yamlFile, runOpts.ResultsFile)
+	}
+	return nil
+}
+
+// runSpecFunction creates and optionally deploys one function of a spec. If previous
+// holds a version that was created but failed to deploy, only the deployment is retried.
+// Created resources are recorded in journal when it is not nil.
+func runSpecFunction(ctx context.Context, client *api.Client, fn functionspec.FunctionDef, previous *specResult, journal *specJournal, deploy, detached bool, wait waitOptions, onStatus func(status string)) specResult {
+	result := specResult{Name: fn.FnName}
+	fail := func(stage string, err error) specResult {
+		result.Status = specStatusFailed
+		result.Stage = stage
+		result.Error = err.Error()
+		result.err = err
+		return result
+	}
+
+	if previous != nil && previous.Status == specStatusFailed && previous.Stage == specStageDeploy && previous.VersionID != "" {
+		result.FunctionID, result.VersionID = previous.FunctionID, previous.VersionID
+	} else {
+		onStatus("creating")
+		var resp *nvcf.CreateFunctionResponse
+		var err error
+		kind := resourceFunction
+		if fn.ExistingFunctionID != "" {
+			kind = resourceVersion
+			resp, err = client.Functions.Versions.New(ctx, fn.ExistingFunctionID, prepareFunctionVersionParamsFromFile(fn))
+		} else {
+			resp, err = client.Functions.New(ctx, prepareFunctionParamsFromFile(fn))
+		}
+		if err != nil {
+			return fail(specStageCreate, err)
+		}
+		result.FunctionID, result.VersionID = resp.Function.ID, resp.Function.VersionID
+		journal.record(kind, fn.FnName, result.FunctionID, result.VersionID)
+	}
+	result.Status = specStatusCreated
+	if !deploy {
+		return result
+	}
+
+	onStatus("deploying")
+	params := newDeploymentParams(fn.InstGPUType, fn.InstType, fn.InstBackend, fn.InstMax, fn.InstMin, fn.InstMaxRequestConcurrency)
+	if _, err := client.FunctionDeployment.Functions.Versions.InitiateDeployment(ctx, result.FunctionID, result.VersionID, params); err != nil {
+		return fail(specStageDeploy, err)
+	}
+	journal.record(resourceDeployment, fn.FnName, result.FunctionID, result.VersionID)
+	result.Status = specStatusDeploying
+	if detached {
+		return result
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, wait.Timeout)
+	defer cancel()
+	err := waitForDeploymentStatus(waitCtx, client, result.FunctionID, result.VersionID, wait.PollInterval, func(status string) {
+		onStatus(strings.ToLower(status))
+	})
+	if err != nil {
+		return fail(specStageDeploy, err)
+	}
+	result.Status = specStatusDeployed
+	return result
+}
+
+// handleSpecInterrupt detaches from, cancels or force-stops the deployments of a
+// spec run that were being waited for when it was interrupted, asking which once
+// for all of them unless the action is set. Stopped deployments are marked failed
+// so that --retry-failed deploys them again.
+func handleSpecInterrupt(cmd *cobra.Command, client *api.Client, results []specResult, action string) {
+	var interrupted []int
+	for i, result := range results {
+		if result.Status == specStatusDeploying && errors.Is(result.err, context.Canceled) {
+			interrupted = append(interrupted, i)
+		}
+	}
+	if len(interrupted) == 0 {
+		return
+	}
+	if action == interruptAsk {
+		action = interruptDetach
+		if output.CanPrompt(cmd) {
+			output.Info(cmd, fmt.Sprintf("%d deployment(s) are still running", len(interrupted)))
+			action = askInterruptAction()
+		}
+	}
+	if action == interruptDetach {
+		output.Info(cmd, fmt.Sprintf("Detached. %d deployment(s) keep running; follow them with 'nvcf function watch'", len(interrupted)))
+		return
+	}
+
+	// the run's context is canceled, but the deployments must still be stopped
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackStopTimeout)
+	defer cancel()
+	for _, i := range interrupted {
+		result := &results[i]
+		_, err := client.FunctionDeployment.Functions.Versions.DeleteDeployment(ctx, result.FunctionID, result.VersionID, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
+			Graceful: nvcf.Bool(action == interruptCancel),
+		})
+		if err != nil {
+			result.Error = fmt.Sprintf("interrupted, and the deployment could not be stopped: %v", err)
+			continue
+		}
+		result.Status, result.Stage, result.Error = specStatusFailed, specStageDeploy, "deployment cancelled after an interrupt"
+	}
+}
+
+// markRolledBack sets the status of every result whose function or version was deleted by a rollback
+func markRolledBack(results []specResult, rollback []rollbackResult) {
+	for _, undone := range rollback {
+		if undone.Err != nil || undone.Kind == resourceDeployment {
+			continue
+		}
+		for i := range results {
+			if results[i].FunctionID == undone.FunctionID && results[i].VersionID == undone.VersionID {
+				results[i].Status, results[i].Stage = specStatusReverted, ""
+			}
+		}
+	}
+}
+
+// failedFunctions selects the functions of the spec to run again: those whose previous result
+// failed, was skipped or was rolled back, and those without a previous result. A result belongs
+// to the function at its index in the spec, provided the name still matches; a result whose
+// function was renamed is dropped, since the function is run again under its new name. It
+// returns the indexes of the functions to run with their previous results, if any, and the
+// results kept so that the new results file stays complete, with one result per index.
+func failedFunctions(functions []functionspec.FunctionDef, previous *specResults) ([]int, []*specResult, []specResult) {
+	byIndex := make([]*specResult, len(functions))
+	var kept []specResult
+	for i := range previous.Results {
+		result := &previous.Results[i]
+		switch {
+		case result.Index < 0 || result.Index >= len(functions):
+			// the function is no longer in the spec; keep its result on record
+			kept = append(kept, *result)
+		case functions[result.Index].FnName == result.Name && byIndex[result.Index] == nil:
+			byIndex[result.Index] = result
+		}
+	}
+
+	var retry []int
+	var retryResults []*specResult
+	for i, result := range byIndex {
+		if result != nil && result.Status != specStatusFailed && result.Status != specStatusSkipped && result.Status != specStatusReverted {
+			kept = append(kept, *result)
+			continue
+		}
+		retry = append(retry, i)
+		retryResults = append(retryResults, result)
+	}
+	return retry, retryResults, kept
+}
+
+// sortResultsBySpec orders results like the functions in the spec
+func sortResultsBySpec(results []specResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Index < results[j].Index
+	})
+}
+
+func defaultResultsFile(yamlFile string) string {
+	return strings.TrimSuffix(yamlFile, filepath.Ext(yamlFile)) + ".results.json"
+}
+
+func readSpecResults(path string) (*specResults, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results specResults
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
+
+func writeSpecResults(path string, results *specResults) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func printSpecSummary(cmd *cobra.Command, results *specResults, resultsFile string) {
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		}
+		return
+	}
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		return
+	}
+
+	counts := map[string]int{}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Name", "Status", "Function ID", "Version ID", "Error"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, result := range results.Results {
+		counts[result.Status]++
+		table.Append([]string{result.Name, result.Status, result.FunctionID, result.VersionID, result.Error})
+	}
+	fmt.Fprintln(cmd.OutOrStdout())
+	table.Render()
+	summary := fmt.Sprintf("%d created, %d deployed, %d deploying, %d failed",
+		counts[specStatusCreated], counts[specStatusDeployed], counts[specStatusDeploying], counts[specStatusFailed])
+	for _, status := range []string{specStatusSkipped, specStatusReverted} {
+		if counts[status] > 0 {
+			summary += fmt.Sprintf(", %d %s", counts[status], status)
+		}
+	}
+	if resultsFile != "" {
+		summary += fmt.Sprintf(". Results written to %s", resultsFile)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "\n%s\n", summary)
+}
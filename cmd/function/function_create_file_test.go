@@ -0,0 +1,207 @@
+package function
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brevdev/nvcf/functionspec"
+)
+
+func TestFailedFunctions(t *testing.T) {
+	functions := []functionspec.FunctionDef{{FnName: "echo"}, {FnName: "echo"}, {FnName: "chat"}, {FnName: "embed"}}
+	tests := []struct {
+		name      string
+		previous  []specResult
+		wantRetry []int
+		wantKept  []int
+	}{
+		{
+			name: "same name keyed by index",
+			previous: []specResult{
+				{Index: 0, Name: "echo", Status: specStatusDeployed},
+				{Index: 1, Name: "echo", Status: specStatusFailed},
+				{Index: 2, Name: "chat", Status: specStatusDeployed},
+				{Index: 3, Name: "embed", Status: specStatusDeployed},
+			},
+			wantRetry: []int{1},
+			wantKept:  []int{0, 2, 3},
+		},
+		{
+			name: "functions without a result are run",
+			previous: []specResult{
+				{Index: 0, Name: "echo", Status: specStatusDeployed},
+				{Index: 2, Name: "chat", Status: specStatusSkipped},
+			},
+			wantRetry: []int{1, 2, 3},
+			wantKept:  []int{0},
+		},
+		{
+			name: "results of functions no longer in the spec are kept",
+			previous: []specResult{
+				{Index: 0, Name: "echo", Status: specStatusDeployed},
+				{Index: 1, Name: "echo", Status: specStatusDeployed},
+				{Index: 2, Name: "chat", Status: specStatusDeployed},
+				{Index: 3, Name: "embed", Status: specStatusReverted},
+				{Index: 7, Name: "gone", Status: specStatusFailed},
+			},
+			wantRetry: []int{3},
+			wantKept:  []int{7, 0, 1, 2},
+		},
+		{
+			name: "results of renamed functions are dropped and run again",
+			previous: []specResult{
+				{Index: 0, Name: "echo", Status: specStatusDeployed},
+				{Index: 1, Name: "echo", Status: specStatusDeployed},
+				{Index: 1, Name: "echo", Status: specStatusFailed},
+				{Index: 2, Name: "old-chat", Status: specStatusFailed},
+				{Index: 3, Name: "old-embed", Status: specStatusDeployed},
+			},
+			wantRetry: []int{2, 3},
+			wantKept:  []int{0, 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry, previous, kept := failedFunctions(functions, &specResults{Results: tt.previous})
+			if !reflect.DeepEqual(retry, tt.wantRetry) {
+				t.Errorf("retry = %v, want %v", retry, tt.wantRetry)
+			}
+			for i, index := range retry {
+				if previous[i] != nil && (previous[i].Index != index || previous[i].Name != functions[index].FnName) {
+					t.Errorf("previous result of %d is %+v", index, *previous[i])
+				}
+			}
+			var keptIndexes []int
+			for _, result := range kept {
+				keptIndexes = append(keptIndexes, result.Index)
+			}
+			if !reflect.DeepEqual(keptIndexes, tt.wantKept) {
+				t.Errorf("kept = %v, want %v", keptIndexes, tt.wantKept)
+			}
+			// every index ends up with a single result once the retried functions have run
+			seen := map[int]bool{}
+			for _, index := range append(keptIndexes, retry...) {
+				if seen[index] {
+					t.Errorf("index %d is both kept and retried, or kept twice", index)
+				}
+				seen[index] = true
+			}
+		})
+	}
+}
+
+// twoDeployedFunctions is a spec of two functions to deploy
+const twoDeployedFunctions = `fn_image: nvcr.io/org/app:1
+functions:
+  - name: echo
+    inferenceUrl: /echo
+    inst_backend: GFN
+    inst_gpu_type: L40
+    inst_type: gl40_1.br20_2xlarge
+  - name: chat
+    inferenceUrl: /chat
+    inst_backend: GFN
+    inst_gpu_type: L40
+    inst_type: gl40_1.br20_2xlarge
+`
+
+// interruptWhenDeployed presses Ctrl-C once every function of twoDeployedFunctions is
+// waiting for its deployment, and returns the path of the spec
+func interruptWhenDeployed(t *testing.T, fake *fakeAPI) string {
+	var interrupt sync.Once
+	fake.onPollDeployment = func() {
+		if fake.deployments() == 2 {
+			interrupt.Do(func() {
+				process, _ := os.FindProcess(os.Getpid())
+				if err := process.Signal(os.Interrupt); err != nil {
+					t.Errorf("sending the interrupt: %v", err)
+				}
+			})
+		}
+	}
+	specFile := filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(specFile, []byte(twoDeployedFunctions), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return specFile
+}
+
+func TestAtomicRunRollsBackWhenInterrupted(t *testing.T) {
+	fake := newFakeAPI(t)
+	specFile := interruptWhenDeployed(t, fake)
+
+	cmd := newTestCommand()
+	cmd.SetContext(context.Background())
+	err := createFunctionsFromFile(cmd, fake.client(), specFile, functionspec.Options{Deploy: true}, specRunOptions{
+		Parallel:    2,
+		ResultsFile: filepath.Join(t.TempDir(), "results.json"),
+		Atomic:      true,
+		Wait:        waitOptions{Timeout: time.Minute, PollInterval: 10 * time.Millisecond},
+	})
+	if err == nil || !strings.Contains(err.Error(), "interrupted; every resource created by this run was rolled back") {
+		t.Fatalf("got error %v, want the interrupted run rolled back", err)
+	}
+	for _, version := range []string{"fid-1/versions/vid-1", "fid-2/versions/vid-2"} {
+		stopped := fake.indexOf("DELETE /v2/nvcf/deployments/functions/" + version)
+		deleted := fake.indexOf("DELETE /v2/nvcf/functions/" + version)
+		if stopped < 0 || deleted < stopped {
+			t.Errorf("%s: deployment stopped at request %d and version deleted at request %d, want both in that order:\n%s",
+				version, stopped, deleted, strings.Join(fake.requests, "\n"))
+		}
+	}
+}
+
+func TestInterruptedRunFollowsOnInterrupt(t *testing.T) {
+	tests := []struct {
+		onInterrupt     string
+		wantDeployments int
+		wantStatus      string
+	}{
+		// ask detaches when input is not possible
+		{onInterrupt: interruptAsk, wantDeployments: 2, wantStatus: specStatusDeploying},
+		{onInterrupt: interruptDetach, wantDeployments: 2, wantStatus: specStatusDeploying},
+		{onInterrupt: interruptCancel, wantDeployments: 0, wantStatus: specStatusFailed},
+		{onInterrupt: interruptForce, wantDeployments: 0, wantStatus: specStatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.onInterrupt, func(t *testing.T) {
+			fake := newFakeAPI(t)
+			specFile := interruptWhenDeployed(t, fake)
+			resultsFile := filepath.Join(t.TempDir(), "results.json")
+
+			cmd := newTestCommand()
+			cmd.SetContext(context.Background())
+			err := createFunctionsFromFile(cmd, fake.client(), specFile, functionspec.Options{Deploy: true}, specRunOptions{
+				Parallel:    2,
+				ResultsFile: resultsFile,
+				OnInterrupt: tt.onInterrupt,
+				Wait:        waitOptions{Timeout: time.Minute, PollInterval: 10 * time.Millisecond},
+			})
+			if err == nil || !strings.Contains(err.Error(), "the run was interrupted") {
+				t.Fatalf("got error %v, want the run interrupted", err)
+			}
+			if got := fake.deployments(); got != tt.wantDeployments {
+				t.Errorf("got %d deployments running, want %d", got, tt.wantDeployments)
+			}
+			results, err := readSpecResults(resultsFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, result := range results.Results {
+				if result.Status != tt.wantStatus || result.VersionID == "" {
+					t.Errorf("got result %+v, want status %s", result, tt.wantStatus)
+				}
+			}
+			// stopped deployments are deployed again by --retry-failed
+			if retry, _, _ := failedFunctions([]functionspec.FunctionDef{{FnName: "echo"}, {FnName: "chat"}}, results); len(retry) != 2-tt.wantDeployments {
+				t.Errorf("got %d functions to retry, want %d", len(retry), 2-tt.wantDeployments)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package function
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/brevdev/nvcf/functionspec"
+)
+
+func TestPrepareParamsFromFile(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		// want is the request body of both the new function and the new version
+		want string
+	}{
+		{
+			name: "fn_image and defaults",
+			spec: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+`,
+			want: `{
+				"name": "app",
+				"inferenceUrl": "/infer",
+				"inferencePort": 80,
+				"containerImage": "nvcr.io/org/app:1",
+				"containerArgs": "",
+				"containerEnvironment": [],
+				"apiBodyFormat": "PREDICT_V2",
+				"functionType": "DEFAULT",
+				"description": "",
+				"tags": [],
+				"models": [],
+				"health": {"protocol": "HTTP", "port": 80, "timeout": "PT20S", "expectedStatusCode": 200, "uri": "/health"}
+			}`,
+		},
+		{
+			name: "containerImage override, models, healthUri and timeout",
+			spec: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    inferencePort: 8000
+    containerImage: nvcr.io/org/other:2
+    containerArgs: --verbose
+    custom: true
+    streaming: true
+    healthUri: /ready
+    health:
+      port: 8000
+      timeout: 45
+    containerEnvironment:
+      - key: MODE
+        value: fast
+    models:
+      - name: llama
+        version: "3"
+        uri: ngc://org/llama
+    tags: [team-a]
+`,
+			want: `{
+				"name": "app",
+				"inferenceUrl": "/infer",
+				"inferencePort": 8000,
+				"containerImage": "nvcr.io/org/other:2",
+				"containerArgs": "--verbose",
+				"containerEnvironment": [{"key": "MODE", "value": "fast"}],
+				"apiBodyFormat": "CUSTOM",
+				"functionType": "STREAMING",
+				"description": "",
+				"tags": ["team-a"],
+				"models": [{"name": "llama", "version": "3", "uri": "ngc://org/llama"}],
+				"health": {"protocol": "HTTP", "port": 8000, "timeout": "PT45S", "expectedStatusCode": 200, "uri": "/ready"}
+			}`,
+		},
+		{
+			name: "health.uri wins over healthUri",
+			spec: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    custom: false
+    streaming: false
+    healthUri: /ready
+    health:
+      uri: /live
+      expectedStatusCode: 204
+`,
+			want: `{
+				"name": "app",
+				"inferenceUrl": "/infer",
+				"inferencePort": 80,
+				"containerImage": "nvcr.io/org/app:1",
+				"containerArgs": "",
+				"containerEnvironment": [],
+				"apiBodyFormat": "PREDICT_V2",
+				"functionType": "DEFAULT",
+				"description": "",
+				"tags": [],
+				"models": [],
+				"health": {"protocol": "HTTP", "port": 80, "timeout": "PT20S", "expectedStatusCode": 204, "uri": "/live"}
+			}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := functionspec.Parse("spec.yaml", []byte(tt.spec), functionspec.Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.ApplyDefaults()
+			fn := spec.Functions[0]
+
+			functionBody, err := prepareFunctionParamsFromFile(fn).MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, "new function", functionBody, tt.want)
+
+			versionBody, err := prepareFunctionVersionParamsFromFile(fn).MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, "new version", versionBody, tt.want)
+		})
+	}
+}
+
+func assertJSONEqual(t *testing.T, what string, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue any
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("%s: %v", what, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("%s: bad expectation: %v", what, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("%s request:\ngot  %s\nwant %s", what, got, want)
+	}
+}
@@ -1,86 +1,97 @@
 package function
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
 	"strings"
 
-	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
 	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
 )
 
 func functionDeleteCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "delete <function-id>",
-		Short:   "Delete a function. If you want to delete a specific version, use the --version-id flag.",
-		Long:    "Delete a function. If there is only 1 version, we will delete the function. If there are multiple versions, we will prompt you to specify which version to delete. The --all flag will delete all versions of the function. Deleting a deployed function will change a function status to INACTIVE and using the --force flag will delete the function immediately.",
-		Example: "nvcf function delete fid --version-id vid",
-		Args:    cobra.ExactArgs(1),
-		RunE:    runFunctionDelete,
+		Use:   "delete [function-id]",
+		Short: "Delete a function. If you want to delete a specific version, use the --version-id flag.",
+		Long:  "Delete a function. If there is only 1 version, we will delete the function. If there are multiple versions, we will prompt you to specify which version to delete. The --all flag will delete all versions of the function. With --all or a selection, the versions to be deleted are listed for confirmation unless --yes is set, and functions protected by the nvcf:protected tag or protected_functions in ~/.nvcf/config.json are only deleted with --override-protection. Deleting a deployed function will change a function status to INACTIVE and using the --force flag will delete the function immediately. --tag, --name, --status and --older-than select versions of the function, or of all your functions without a function ID, and delete them a few at a time. A selection only deletes INACTIVE versions unless --status is set.",
+		Example: `nvcf function delete fid --version-id vid
+nvcf function delete --status INACTIVE --older-than 30d
+nvcf function delete --name 'test-*' --tag team=search --yes
+nvcf function delete --tag team=search --status INACTIVE --status ERROR`,
+		Args: cobra.RangeArgs(0, 1),
+		RunE: runFunctionDelete,
 	}
 	cmd.Flags().String("version-id", "", "The ID of the version")
+	cmd.Flags().String("select", "", output.SelectFlagUsage)
 	cmd.Flags().BoolP("all", "a", false, "Delete all versions of the function")
 	cmd.Flags().BoolP("force", "f", false, "Forcefully delete a deployed function")
+	addConfirmationFlags(cmd)
+	addSelectorFlags(cmd, "INACTIVE")
 	return cmd
 }
 
 func runFunctionDelete(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	selector, bulk, err := bulkMode(cmd, args, nvcf.ListFunctionsResponseFunctionsStatusInactive)
+	if err != nil {
+		return err
+	}
+	client := newClient(cmd)
+	if bulk {
+		return runBulk(cmd, client, strings.Join(args, ""), "delete", "deleted", selector, func(ctx context.Context, version nvcf.ListFunctionsResponseFunction) error {
+			return client.Functions.Versions.Delete(ctx, version.ID, version.VersionID)
+		})
+	}
 
 	functionId := args[0]
 	versionId, _ := cmd.Flags().GetString("version-id")
 	all, _ := cmd.Flags().GetBool("all")
 
-	if versionId == "" {
-		versions, err := client.Functions.Versions.List(cmd.Context(), functionId)
+	versions, err := client.Functions.Versions.List(cmd.Context(), functionId)
+	if err != nil {
+		return output.Error(cmd, "Error listing function versions", err)
+	}
+
+	toDelete := versions.Functions
+	if versionId != "" {
+		version, ok := findVersion(versions.Functions, versionId)
+		if !ok {
+			return output.Error(cmd, fmt.Sprintf("Version %s not found in function %s", versionId, functionId), nil)
+		}
+		toDelete = []nvcf.ListFunctionsResponseFunction{version}
+	} else if len(versions.Functions) > 1 && !all {
+		versionId, err = output.SelectVersion(cmd, versions.Functions, "delete")
 		if err != nil {
-			return output.Error(cmd, "Error listing function versions", err)
+			return err
 		}
-		if len(versions.Functions) == 1 {
-			versionId = versions.Functions[0].VersionID
-			output.Info(cmd, "Deleting function")
-			err := client.Functions.Versions.Delete(cmd.Context(), functionId, versionId)
-			if err != nil {
-				return output.Error(cmd, "Error deleting function", err)
-			}
-			output.Success(cmd, "Function deleted successfully")
+		version, _ := findVersion(versions.Functions, versionId)
+		toDelete = []nvcf.ListFunctionsResponseFunction{version}
+	}
+
+	if err := checkProtection(cmd, functionId, versions.Functions); err != nil {
+		return err
+	}
+	if all {
+		confirmed, err := confirmAffected(cmd, client, functionId, "delete", toDelete)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			output.Info(cmd, "Nothing deleted")
 			return nil
-		} else {
-			if all {
-				for _, version := range versions.Functions {
-					output.Info(cmd, fmt.Sprintf("Deleting version %s of function %s", version.VersionID, functionId))
-					err := client.Functions.Versions.Delete(cmd.Context(), functionId, version.VersionID)
-					if err != nil {
-						return output.Error(cmd, "Error deleting function version", err)
-					}
-				}
-				output.Success(cmd, "All versions of function deleted successfully")
-				return nil
-			} else {
-				output.Info(cmd, "Multiple versions found. Please specify a version-id")
-				for _, version := range versions.Functions {
-					output.Info(cmd, fmt.Sprintf("Version ID: %s || Status: %s", version.VersionID, version.Status))
-				}
-				reader := bufio.NewReader(os.Stdin)
-				fmt.Print("Enter version-id: ")
-				versionId, _ = reader.ReadString('\n')
-				versionId = strings.TrimSpace(versionId)
-				err := client.Functions.Versions.Delete(cmd.Context(), functionId, versionId)
-				if err != nil {
-					return output.Error(cmd, "Error deleting function version", err)
-				}
-				output.Success(cmd, "Function deleted successfully")
-			}
 		}
-	} else {
-		output.Info(cmd, fmt.Sprintf("Deleting function %s version %s", functionId, versionId))
-		err := client.Functions.Versions.Delete(cmd.Context(), functionId, versionId)
+	}
+
+	for _, version := range toDelete {
+		output.Info(cmd, fmt.Sprintf("Deleting function %s version %s", functionId, version.VersionID))
+		err := client.Functions.Versions.Delete(cmd.Context(), functionId, version.VersionID)
 		if err != nil {
-			return output.Error(cmd, "Error deleting function", err)
+			return output.Error(cmd, "Error deleting function version", err)
 		}
+	}
+	if len(toDelete) > 1 {
+		output.Success(cmd, "All versions of function deleted successfully")
+	} else {
 		output.Success(cmd, "Function deleted successfully")
 	}
 	return nil
@@ -1,13 +1,9 @@
 package function
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
 	"github.com/spf13/cobra"
 	"github.com/tmc/nvcf-go"
@@ -17,10 +13,13 @@ func functionDeployCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:     "deploy <function-id>",
 		Short:   "Deploy a function",
-		Long:    `Deploy an existing NVCF function. If you want to deploy a specific version, use the --version-id flag.`,
+		Long:    `Deploy an existing NVCF function. If you want to deploy a specific version, use the --version-id flag. In a terminal, leaving out --backend, --gpu or --instance-type opens a picker of the combinations available to your org. Pressing Ctrl-C while waiting for the deployment asks whether to detach, cancel it gracefully or force-stop it; --on-interrupt picks one up front.`,
 		Example: "nvcf function deploy fid --version-id vid --gpu A100 --instance-type g5.4xlarge",
 		Args:    cobra.ExactArgs(1),
 		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if output.CanPrompt(cmd) {
+				return nil
+			}
 			requiredFlags := []string{"gpu", "instance-type", "backend"}
 			for _, flag := range requiredFlags {
 				if err := cmd.MarkFlagRequired(flag); err != nil {
@@ -33,6 +32,7 @@ func functionDeployCmd() *cobra.Command {
 	}
 
 	cmd.Flags().String("version-id", "", "The ID of the version to deploy")
+	cmd.Flags().String("select", "", output.SelectFlagUsage)
 	cmd.Flags().String("gpu", "", "GPU type to use")
 	cmd.Flags().String("instance-type", "", "Instance type to use")
 	cmd.Flags().String("backend", "", "Backend to deploy the function to")
@@ -40,12 +40,16 @@ func functionDeployCmd() *cobra.Command {
 	cmd.Flags().Int64("max-instances", 1, "Maximum number of instances")
 	cmd.Flags().Int64("max-request-concurrency", 1, "Maximum number of concurrent requests")
 	cmd.Flags().BoolP("detached", "d", false, "Detach from the deployment and return to the prompt")
+	addWaitFlags(cmd)
+	addInterruptFlag(cmd)
+	cmd.Flags().Bool("skip-validation", false, "Deploy without checking the GPU, instance type, backend and max instances against the org's cluster groups")
+	addBudgetFlag(cmd)
 
 	return cmd
 }
 
 func runFunctionDeploy(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	client := newClient(cmd)
 	functionId := args[0]
 	versionId, _ := cmd.Flags().GetString("version-id")
 
@@ -55,17 +59,18 @@ func runFunctionDeploy(cmd *cobra.Command, args []string) error {
 			return output.Error(cmd, "Error listing function versions", err)
 		}
 
-		if len(versions.Functions) == 1 {
-			versionId = versions.Functions[0].VersionID
-		} else {
-			output.Info(cmd, "Multiple versions found. Please specify a version-id")
-			for _, version := range versions.Functions {
-				output.Info(cmd, fmt.Sprintf("Version ID: %s || Status: %s", version.VersionID, version.Status))
-			}
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Enter version-id: ")
-			versionId, _ = reader.ReadString('\n')
-			versionId = strings.TrimSpace(versionId)
+		versionId, err = output.SelectVersion(cmd, versions.Functions, "deploy")
+		if err != nil {
+			return err
+		}
+	}
+
+	if wantsDeployWizard(cmd) {
+		if err := cmd.Flags().Set("version-id", versionId); err != nil {
+			return output.Error(cmd, "Error setting --version-id", err)
+		}
+		if err := runDeployWizard(cmd, args); err != nil {
+			return err
 		}
 	}
 
@@ -86,6 +91,13 @@ func runFunctionDeploy(cmd *cobra.Command, args []string) error {
 	maxRequestConcurrency, _ := cmd.Flags().GetInt64("max-request-concurrency")
 	detached, _ := cmd.Flags().GetBool("detached")
 
+	if err := validateDeployment(cmd, backend, gpu, instanceType, maxInstances); err != nil {
+		return err
+	}
+	if err := checkHourlyBudget(cmd, budgetedDeployment{GPU: gpu, InstanceType: instanceType, MaxInstances: maxInstances}); err != nil {
+		return err
+	}
+
 	deploymentParams := nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams{
 		DeploymentSpecifications: nvcf.F([]nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification{{
 			GPU:                   nvcf.String(gpu),
@@ -115,7 +127,7 @@ func runFunctionDeploy(cmd *cobra.Command, args []string) error {
 		if fn.Function.Status != nvcf.FunctionResponseFunctionStatusInactive {
 			output.Info(cmd, fmt.Sprintf("This function is currently %s. ", fn.Function.Status))
 			output.Info(cmd, "Creating new version and deploying...")
-			newVersionToDeploy, err := createNewVersion(cmd, client, fn.Function)
+			newVersionToDeploy, err := createNewVersion(cmd, client, fn.Function, nil)
 			if err != nil {
 				return output.Error(cmd, "Error creating new version to deploy", err)
 			}
@@ -142,8 +154,8 @@ func runFunctionDeploy(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func createNewVersion(cmd *cobra.Command, client *api.Client, function nvcf.FunctionResponseFunction) (string, error) {
-	newVersion, err := client.Functions.Versions.New(cmd.Context(), function.ID, nvcf.FunctionVersionNewParams{
+func createNewVersion(cmd *cobra.Command, client *api.Client, function nvcf.FunctionResponseFunction, secrets []nvcf.FunctionVersionNewParamsSecret) (string, error) {
+	params := nvcf.FunctionVersionNewParams{
 		Name:                 nvcf.String(function.Name),
 		InferenceURL:         nvcf.String(function.InferenceURL),
 		InferencePort:        nvcf.Int(function.InferencePort),
@@ -162,7 +174,14 @@ func createNewVersion(cmd *cobra.Command, client *api.Client, function nvcf.Func
 			ExpectedStatusCode: nvcf.F(function.Health.ExpectedStatusCode),
 			Uri:                nvcf.String(function.Health.Uri),
 		}),
-	})
+	}
+	if resources := mapResponseResourcesToNewResources(function.Resources); len(resources) > 0 {
+		params.Resources = nvcf.F(resources)
+	}
+	if len(secrets) > 0 {
+		params.Secrets = nvcf.F(secrets)
+	}
+	newVersion, err := client.Functions.Versions.New(cmd.Context(), function.ID, params)
 	if err != nil {
 		return "", output.Error(cmd, "Error creating new version", err)
 	}
@@ -181,6 +200,18 @@ func mapResponseContainerEnvToNewContainerEnv(containerEnv []nvcf.FunctionRespon
 	return newContainerEnv
 }
 
+func mapResponseResourcesToNewResources(resources []nvcf.FunctionResponseFunctionResource) []nvcf.FunctionVersionNewParamsResource {
+	var newResources []nvcf.FunctionVersionNewParamsResource
+	for _, resource := range resources {
+		newResources = append(newResources, nvcf.FunctionVersionNewParamsResource{
+			Name:    nvcf.F(resource.Name),
+			Uri:     nvcf.F(resource.Uri),
+			Version: nvcf.F(resource.Version),
+		})
+	}
+	return newResources
+}
+
 func mapResponseModelsToNewModels(models []nvcf.FunctionResponseFunctionModel) []nvcf.FunctionVersionNewParamsModel {
 	var newModels []nvcf.FunctionVersionNewParamsModel
 	for _, model := range models {
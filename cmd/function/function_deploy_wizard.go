@@ -0,0 +1,295 @@
+package function
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/brevdev/nvcf/cmd/gpu"
+	"github.com/brevdev/nvcf/output"
+	"github.com/rivo/tview"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// deploymentFlags are the flags the deploy wizard asks for when any of
+// backend, gpu and instance-type is missing
+var deploymentFlags = []string{"backend", "gpu", "instance-type", "min-instances", "max-instances", "max-request-concurrency"}
+
+// wantsDeployWizard reports whether the deployment is missing its backend, GPU
+// or instance type and can be asked for interactively
+func wantsDeployWizard(cmd *cobra.Command) bool {
+	for _, name := range []string{"backend", "gpu", "instance-type"} {
+		if !cmd.Flags().Changed(name) {
+			return output.CanPrompt(cmd)
+		}
+	}
+	return false
+}
+
+// runDeployWizard asks for the deployment with a picker of the backends, GPUs and
+// instance types available to the org and sets the deployment flags to the
+// answers. It then prints the command that deploys the same way without the wizard.
+func runDeployWizard(cmd *cobra.Command, args []string) error {
+	output.Info(cmd, "Looking up the GPUs available to your org...")
+	combinations, err := gpu.AvailableCombinations(cmd.Context())
+	if err != nil {
+		return output.Error(cmd, "Error listing the GPUs available to your org", err)
+	}
+	if len(combinations) == 0 {
+		return output.Error(cmd, "Your org has no GPUs available to deploy to", nil)
+	}
+
+	wizard := newDeployWizard(cmd, combinations)
+	if err := wizard.app.Run(); err != nil {
+		return output.Error(cmd, "error running UI", err)
+	}
+	if !wizard.confirmed {
+		return output.Error(cmd, "Deployment cancelled", nil)
+	}
+
+	for name, value := range wizard.values() {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return output.Error(cmd, fmt.Sprintf("Error setting --%s", name), err)
+		}
+	}
+	output.Info(cmd, "To deploy the same way without the wizard, run:")
+	fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", equivalentCommand(cmd, args))
+	return nil
+}
+
+type deployWizard struct {
+	app          *tview.Application
+	pages        *tview.Pages
+	form         *tview.Form
+	status       *tview.TextView
+	combinations []gpu.Combination
+	confirmed    bool
+
+	backend, gpu, instanceType *tview.DropDown
+	min, max, concurrency      *tview.InputField
+}
+
+func newDeployWizard(cmd *cobra.Command, combinations []gpu.Combination) *deployWizard {
+	w := &deployWizard{
+		app:          tview.NewApplication(),
+		pages:        tview.NewPages(),
+		form:         tview.NewForm(),
+		status:       tview.NewTextView().SetDynamicColors(true),
+		combinations: combinations,
+	}
+	initial := func(name string) string {
+		value, _ := cmd.Flags().GetString(name)
+		return value
+	}
+	number := func(name string) string {
+		value, _ := cmd.Flags().GetInt64(name)
+		return strconv.FormatInt(value, 10)
+	}
+
+	w.backend = tview.NewDropDown().SetLabel("Backend")
+	w.gpu = tview.NewDropDown().SetLabel("GPU")
+	w.instanceType = tview.NewDropDown().SetLabel("Instance type")
+	w.min = tview.NewInputField().SetLabel("Min instances").SetText(number("min-instances")).SetFieldWidth(6).SetAcceptanceFunc(tview.InputFieldInteger)
+	w.max = tview.NewInputField().SetLabel("Max instances").SetText(number("max-instances")).SetFieldWidth(6).SetAcceptanceFunc(tview.InputFieldInteger)
+	w.concurrency = tview.NewInputField().SetLabel("Max request concurrency").SetText(number("max-request-concurrency")).SetFieldWidth(6).SetAcceptanceFunc(tview.InputFieldInteger)
+
+	// each choice narrows the options of the next
+	w.instanceType.SetOptions(nil, func(string, int) { w.showAvailable() })
+	w.gpu.SetOptions(nil, func(gpuType string, _ int) {
+		_, backend := w.backend.GetCurrentOption()
+		selectOption(w.instanceType, w.options(func(c gpu.Combination) (string, bool) {
+			return c.InstanceType, c.Backend == backend && c.GPU == gpuType
+		}), initial("instance-type"))
+	})
+	w.backend.SetOptions(nil, func(backend string, _ int) {
+		selectOption(w.gpu, w.options(func(c gpu.Combination) (string, bool) {
+			return c.GPU, c.Backend == backend
+		}), initial("gpu"))
+	})
+	selectOption(w.backend, w.options(func(c gpu.Combination) (string, bool) {
+		return c.Backend, true
+	}), initial("backend"))
+
+	w.form.
+		AddFormItem(w.backend).
+		AddFormItem(w.gpu).
+		AddFormItem(w.instanceType).
+		AddFormItem(w.min).
+		AddFormItem(w.max).
+		AddFormItem(w.concurrency).
+		AddButton("Review", w.review).
+		AddButton("Cancel", w.app.Stop).
+		SetCancelFunc(w.app.Stop)
+	w.form.SetBorder(true).SetTitle(" Deploy function ").SetTitleAlign(tview.AlignLeft)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(w.form, 0, 1, true).
+		AddItem(w.status, 3, 0, false)
+	w.pages.AddPage("form", layout, true, true)
+	w.app.SetRoot(w.pages, true).EnableMouse(true)
+	return w
+}
+
+// options lists the distinct values picked from the combinations, in the order the API returned them
+func (w *deployWizard) options(pick func(gpu.Combination) (string, bool)) []string {
+	var options []string
+	seen := map[string]bool{}
+	for _, combination := range w.combinations {
+		if value, ok := pick(combination); ok && !seen[value] {
+			seen[value] = true
+			options = append(options, value)
+		}
+	}
+	return options
+}
+
+// selectOption replaces the options of a drop-down. It keeps the current
+// option if it is still offered, else selects preferred or the first option.
+func selectOption(dropDown *tview.DropDown, options []string, preferred string) {
+	_, current := dropDown.GetCurrentOption()
+	selected := 0
+	for i, option := range options {
+		if option == preferred && options[selected] != current {
+			selected = i
+		}
+		if option == current {
+			selected = i
+		}
+	}
+	for dropDown.GetOptionCount() > 0 {
+		dropDown.RemoveOption(0)
+	}
+	for _, option := range options {
+		dropDown.AddOption(option, nil)
+	}
+	dropDown.SetCurrentOption(selected)
+}
+
+// selected returns the combination currently picked in the drop-downs
+func (w *deployWizard) selected() (gpu.Combination, bool) {
+	_, backend := w.backend.GetCurrentOption()
+	_, gpuType := w.gpu.GetCurrentOption()
+	_, instanceType := w.instanceType.GetCurrentOption()
+	for _, combination := range w.combinations {
+		if combination.Backend == backend && combination.GPU == gpuType && combination.InstanceType == instanceType {
+			return combination, true
+		}
+	}
+	return gpu.Combination{}, false
+}
+
+func (w *deployWizard) showAvailable() {
+	combination, ok := w.selected()
+	switch {
+	case !ok:
+		w.status.SetText("")
+	case combination.Available >= 0:
+		w.status.SetText(fmt.Sprintf(" Your org can start %d more instance(s) of this type.", combination.Available))
+	default:
+		w.status.SetText(" Your org's capacity for this type is not known.")
+	}
+}
+
+// review checks the answers and shows them for confirmation
+func (w *deployWizard) review() {
+	combination, ok := w.selected()
+	if !ok {
+		w.status.SetText(" [red]Pick a backend, GPU and instance type.[-]")
+		return
+	}
+	values := w.values()
+	minInstances, _ := strconv.ParseInt(values["min-instances"], 10, 64)
+	maxInstances, _ := strconv.ParseInt(values["max-instances"], 10, 64)
+	concurrency, _ := strconv.ParseInt(values["max-request-concurrency"], 10, 64)
+	var problem string
+	switch {
+	case maxInstances < 1:
+		problem = "Max instances must be at least 1."
+	case minInstances < 0 || minInstances > maxInstances:
+		problem = "Min instances must be between 0 and max instances."
+	case concurrency < 1:
+		problem = "Max request concurrency must be at least 1."
+	case combination.Available >= 0 && maxInstances > int64(combination.Available):
+		problem = fmt.Sprintf("Your org can only start %d more instance(s) of this type.", combination.Available)
+	}
+	if problem != "" {
+		w.status.SetText(fmt.Sprintf(" [red]%s[-]", problem))
+		return
+	}
+
+	summary := fmt.Sprintf("Deploy with:\n\nBackend: %s\nGPU: %s\nInstance type: %s\nInstances: %d to %d\nMax request concurrency: %d",
+		combination.Backend, combination.GPU, combination.InstanceType, minInstances, maxInstances, concurrency)
+	confirm := tview.NewModal().
+		SetText(summary).
+		AddButtons([]string{"Deploy", "Back", "Cancel"}).
+		SetDoneFunc(func(_ int, label string) {
+			switch label {
+			case "Deploy":
+				w.confirmed = true
+				w.app.Stop()
+			case "Back":
+				w.pages.RemovePage("confirm")
+			default:
+				w.app.Stop()
+			}
+		})
+	w.pages.AddPage("confirm", confirm, true, true)
+}
+
+// values returns the answers as flag values
+func (w *deployWizard) values() map[string]string {
+	_, backend := w.backend.GetCurrentOption()
+	_, gpuType := w.gpu.GetCurrentOption()
+	_, instanceType := w.instanceType.GetCurrentOption()
+	number := func(field *tview.InputField) string {
+		if field.GetText() == "" {
+			return "0"
+		}
+		return field.GetText()
+	}
+	return map[string]string{
+		"backend":                 backend,
+		"gpu":                     gpuType,
+		"instance-type":           instanceType,
+		"min-instances":           number(w.min),
+		"max-instances":           number(w.max),
+		"max-request-concurrency": number(w.concurrency),
+	}
+}
+
+// equivalentCommand renders the command line with every flag that is set
+func equivalentCommand(cmd *cobra.Command, args []string) string {
+	parts := []string{cmd.CommandPath()}
+	for _, arg := range args {
+		parts = append(parts, shellQuote(arg))
+	}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		switch value := flag.Value.(type) {
+		case pflag.SliceValue:
+			for _, item := range value.GetSlice() {
+				parts = append(parts, "--"+flag.Name, shellQuote(item))
+			}
+		default:
+			if flag.Value.Type() == "bool" {
+				if flag.Value.String() == "true" {
+					parts = append(parts, "--"+flag.Name)
+				} else {
+					parts = append(parts, "--"+flag.Name+"=false")
+				}
+				return
+			}
+			parts = append(parts, "--"+flag.Name, shellQuote(flag.Value.String()))
+		}
+	})
+	return strings.Join(parts, " ")
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./:=,@+", r))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,314 @@
+package function
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/flagutil"
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces the value of environment variables that look like secrets where they are displayed
+const redactedValue = "<redacted>"
+
+// sensitiveEnvKeyMarkers are runs of _-separated words in environment variable names whose values are redacted
+var sensitiveEnvKeyMarkers = [][]string{
+	{"SECRET"}, {"SECRETS"}, {"TOKEN"}, {"TOKENS"}, {"PASSWORD"}, {"PASSWD"}, {"APIKEY"}, {"CREDENTIAL"}, {"CREDENTIALS"}, {"AUTH"},
+	{"API", "KEY"}, {"PRIVATE", "KEY"}, {"ACCESS", "KEY"},
+}
+
+// envVarName matches the environment variable names a ${VAR} reference can hold
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func functionExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [function-id]",
+		Short: "Export a function as a YAML function specification",
+		Long: `Export an existing function as a YAML specification that can be consumed by 'nvcf function create --file'.
+If a version-id is not provided, the active version is exported. If there is no active version, the most recently created version is exported.
+The current deployment specification of the version is exported as inst_* fields. Values of environment variables that look like secrets are exported as ${NAME} references unless --show-secrets is set, so that 'create --file' takes them from --var or the environment.
+With --all, functions that cannot be exported are reported and skipped.`,
+		Example: "nvcf function export fid --version-id vid --file function.yaml\nnvcf function export --all > functions.yaml",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    runFunctionExport,
+	}
+	cmd.Flags().String("version-id", "", "The ID of the version to export")
+	cmd.Flags().Bool("all", false, "Export every function in the org")
+	cmd.Flags().StringP("file", "f", "", "Write the specification to a file instead of stdout")
+	cmd.Flags().Bool("keep-id", false, "Set existingFunctionID so that 'create --file' adds a new version to the exported function")
+	cmd.Flags().Bool("show-secrets", false, "Do not redact environment variables that look like secrets")
+	return cmd
+}
+
+func runFunctionExport(cmd *cobra.Command, args []string) error {
+	client := newClient(cmd)
+	versionID, _ := cmd.Flags().GetString("version-id")
+	all, _ := cmd.Flags().GetBool("all")
+	file, _ := cmd.Flags().GetString("file")
+	keepID, _ := cmd.Flags().GetBool("keep-id")
+	showSecrets, _ := cmd.Flags().GetBool("show-secrets")
+
+	if all == (len(args) == 1) {
+		return output.Error(cmd, "Please provide either a function-id or the --all flag", nil)
+	}
+	if all && versionID != "" {
+		return output.Error(cmd, "The --version-id flag cannot be used with --all", nil)
+	}
+
+	var defs []functionspec.FunctionDef
+	failed := 0
+	if all {
+		functions, err := client.Functions.List(cmd.Context(), nvcf.FunctionListParams{
+			Visibility: nvcf.F([]nvcf.FunctionListParamsVisibility{nvcf.FunctionListParamsVisibilityPrivate}),
+		})
+		if err != nil {
+			return output.Error(cmd, "Error listing functions", err)
+		}
+		for _, functionID := range sortedFunctionIDs(functions.Functions) {
+			version := versionToExport(functionVersions(functions.Functions, functionID))
+			def, warnings, err := exportFunctionDef(cmd.Context(), client, functionID, version.VersionID, keepID, showSecrets)
+			if err != nil {
+				failed++
+				fmt.Fprintf(cmd.ErrOrStderr(), "Error exporting function %s: %v\n", functionID, err)
+				continue
+			}
+			printExportWarnings(cmd, warnings)
+			defs = append(defs, def)
+		}
+	} else {
+		functionID := args[0]
+		if versionID == "" {
+			versions, err := client.Functions.Versions.List(cmd.Context(), functionID)
+			if err != nil {
+				return output.Error(cmd, "Error listing function versions", err)
+			}
+			if len(versions.Functions) == 0 {
+				return output.Error(cmd, fmt.Sprintf("No versions found for function %s", functionID), nil)
+			}
+			versionID = versionToExport(versions.Functions).VersionID
+		}
+		def, warnings, err := exportFunctionDef(cmd.Context(), client, functionID, versionID, keepID, showSecrets)
+		if err != nil {
+			return output.Error(cmd, fmt.Sprintf("Error exporting function %s", functionID), err)
+		}
+		printExportWarnings(cmd, warnings)
+		defs = append(defs, def)
+	}
+
+	data, err := marshalFunctionSpec(buildExportSpec(defs))
+	if err != nil {
+		return output.Error(cmd, "Error formatting YAML", err)
+	}
+
+	if file == "" {
+		if _, err := cmd.OutOrStdout().Write(data); err != nil {
+			return err
+		}
+	} else {
+		if err := os.WriteFile(file, data, 0600); err != nil {
+			return output.Error(cmd, "Error writing YAML file", err)
+		}
+		output.Success(cmd, fmt.Sprintf("Exported %d function(s) to %s", len(defs), file))
+	}
+	if failed > 0 {
+		return output.Error(cmd, fmt.Sprintf("%d function(s) could not be exported", failed), nil)
+	}
+	return nil
+}
+
+// printExportWarnings writes warnings to stderr, keeping them out of a spec written to stdout
+func printExportWarnings(cmd *cobra.Command, warnings []string) {
+	for _, warning := range warnings {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %s\n", warning)
+	}
+}
+
+// exportFunctionDef fetches a function version and its deployment and converts them into a
+// FunctionDef, with warnings about what the FunctionDef leaves out
+func exportFunctionDef(ctx context.Context, client *api.Client, functionID, versionID string, keepID, showSecrets bool) (functionspec.FunctionDef, []string, error) {
+	fn, err := client.Functions.Versions.Get(ctx, functionID, versionID, nvcf.FunctionVersionGetParams{
+		IncludeSecrets: nvcf.Bool(false),
+	})
+	if err != nil {
+		return functionspec.FunctionDef{}, nil, err
+	}
+
+	def, warnings, err := functionDefFromResponse(fn.Function, showSecrets)
+	if err != nil {
+		return functionspec.FunctionDef{}, nil, err
+	}
+	if keepID {
+		def.ExistingFunctionID = functionID
+	}
+
+	if fn.Function.Status != nvcf.FunctionResponseFunctionStatusInactive {
+		deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(ctx, functionID, versionID)
+		if err != nil {
+			return functionspec.FunctionDef{}, nil, err
+		}
+		specs := deployment.Deployment.DeploymentSpecifications
+		if len(specs) > 0 {
+			applyDeploymentSpec(&def, specs[0])
+		}
+		if len(specs) > 1 {
+			warnings = append(warnings, fmt.Sprintf("function %s version %s has %d deployment specifications; only the first (%s) is exported",
+				functionID, versionID, len(specs), describeDeploymentSpec(specs[0])))
+		}
+	}
+	return def, warnings, nil
+}
+
+// functionDefFromResponse converts a function version into the FunctionDef consumed by 'create --file'.
+// Unless showSecrets is set, values that look like secrets become ${KEY} references.
+func functionDefFromResponse(fn nvcf.FunctionResponseFunction, showSecrets bool) (functionspec.FunctionDef, []string, error) {
+	custom := fn.APIBodyFormat == nvcf.FunctionResponseFunctionAPIBodyFormatCustom
+	streaming := fn.FunctionType == nvcf.FunctionResponseFunctionFunctionTypeStreaming
+	def := functionspec.FunctionDef{
+		FnName:         fn.Name,
+		InferenceURL:   fn.InferenceURL,
+		InferencePort:  fn.InferencePort,
+		ContainerImage: fn.ContainerImage,
+		ContainerArgs:  escapeVarRefs(fn.ContainerArgs),
+		Custom:         &custom,
+		Description:    escapeVarRefs(fn.Description),
+		Streaming:      &streaming,
+		Tags:           fn.Tags,
+		Health: functionspec.HealthCheck{
+			Protocol:           string(fn.Health.Protocol),
+			Port:               fn.Health.Port,
+			ExpectedStatusCode: fn.Health.ExpectedStatusCode,
+			Uri:                fn.Health.Uri,
+		},
+	}
+	if fn.Health.Uri == "" {
+		def.HealthUri = fn.HealthUri
+	}
+	if fn.Health.Timeout != "" {
+		timeout, err := flagutil.ISO8601ToDuration(fn.Health.Timeout)
+		if err != nil {
+			return functionspec.FunctionDef{}, nil, err
+		}
+		def.Health.Timeout = timeout
+	}
+	var warnings []string
+	for _, env := range fn.ContainerEnvironment {
+		value := escapeVarRefs(env.Value)
+		if !showSecrets && isSensitiveEnvKey(env.Key) {
+			if !envVarName.MatchString(env.Key) {
+				warnings = append(warnings, fmt.Sprintf("%s: environment variable %s looks like a secret and was left out", fn.Name, env.Key))
+				continue
+			}
+			value = "${" + env.Key + "}"
+		}
+		def.ContainerEnvironment = append(def.ContainerEnvironment, functionspec.EnvVar{Key: env.Key, Value: value})
+	}
+	for _, model := range fn.Models {
+		def.Models = append(def.Models, functionspec.ModelDef{Name: model.Name, Version: model.Version, Uri: model.Uri})
+	}
+	return def, warnings, nil
+}
+
+func applyDeploymentSpec(def *functionspec.FunctionDef, spec nvcf.DeploymentResponseDeploymentDeploymentSpecification) {
+	def.InstBackend = spec.Backend
+	def.InstGPUType = spec.GPU
+	def.InstType = spec.InstanceType
+	def.InstMin = spec.MinInstances
+	def.InstMax = spec.MaxInstances
+	def.InstMaxRequestConcurrency = spec.MaxRequestConcurrency
+}
+
+// buildExportSpec hoists the first function's image to fn_image and keeps containerImage only where it differs
+func buildExportSpec(defs []functionspec.FunctionDef) functionspec.FunctionSpec {
+	spec := functionspec.FunctionSpec{Functions: defs}
+	if len(defs) == 0 {
+		return spec
+	}
+	spec.FnImage = defs[0].ContainerImage
+	for i := range spec.Functions {
+		if spec.Functions[i].ContainerImage == spec.FnImage {
+			spec.Functions[i].ContainerImage = ""
+		}
+	}
+	return spec
+}
+
+func marshalFunctionSpec(spec functionspec.FunctionSpec) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(spec); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// escapeVarRefs escapes ${ as $${ so that 'create --file' keeps it rather than substituting a variable
+func escapeVarRefs(s string) string {
+	return strings.ReplaceAll(s, "${", "$${")
+}
+
+// isSensitiveEnvKey reports whether an environment variable looks like a secret. Names are
+// compared by whole _-separated words, so that AUTH_TOKEN matches and AUTHOR does not.
+func isSensitiveEnvKey(key string) bool {
+	words := strings.Split(strings.ToUpper(key), "_")
+	for _, marker := range sensitiveEnvKeyMarkers {
+		for i := 0; i+len(marker) <= len(words); i++ {
+			if slices.Equal(words[i:i+len(marker)], marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// versionToExport prefers the active version and falls back to the most recently created one
+func versionToExport(versions []nvcf.ListFunctionsResponseFunction) nvcf.ListFunctionsResponseFunction {
+	sorted := make([]nvcf.ListFunctionsResponseFunction, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	for _, version := range sorted {
+		if version.Status == nvcf.ListFunctionsResponseFunctionsStatusActive {
+			return version
+		}
+	}
+	return sorted[0]
+}
+
+func functionVersions(functions []nvcf.ListFunctionsResponseFunction, functionID string) []nvcf.ListFunctionsResponseFunction {
+	var versions []nvcf.ListFunctionsResponseFunction
+	for _, fn := range functions {
+		if fn.ID == functionID {
+			versions = append(versions, fn)
+		}
+	}
+	return versions
+}
+
+func sortedFunctionIDs(functions []nvcf.ListFunctionsResponseFunction) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, fn := range functions {
+		if !seen[fn.ID] {
+			seen[fn.ID] = true
+			ids = append(ids, fn.ID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
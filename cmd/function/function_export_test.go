@@ -0,0 +1,75 @@
+package function
+
+import (
+	"testing"
+
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/tmc/nvcf-go"
+)
+
+func TestIsSensitiveEnvKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"API_TOKEN", true},
+		{"hf_token", true},
+		{"NGC_API_KEY", true},
+		{"DB_PASSWORD", true},
+		{"AUTH", true},
+		{"AUTH_HEADER", true},
+		{"AWS_SECRET_ACCESS_KEY", true},
+		{"AUTHOR", false},
+		{"OAUTH_CALLBACK_URL", false},
+		{"TOKENIZER_PATH", false},
+		{"KEY", false},
+		{"MODEL_NAME", false},
+	}
+	for _, tt := range tests {
+		if got := isSensitiveEnvKey(tt.key); got != tt.want {
+			t.Errorf("isSensitiveEnvKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	fn := nvcf.FunctionResponseFunction{
+		Name:           "echo",
+		InferenceURL:   "/echo",
+		ContainerImage: "nvcr.io/org/echo:1",
+		ContainerArgs:  "sh -c 'exec serve ${PORT}'",
+		ContainerEnvironment: []nvcf.FunctionResponseFunctionContainerEnvironment{
+			{Key: "API_TOKEN", Value: "s3cret"},
+			{Key: "AUTHOR", Value: "jane"},
+		},
+	}
+	def, warnings, err := functionDefFromResponse(fn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+	data, err := marshalFunctionSpec(buildExportSpec([]functionspec.FunctionDef{def}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := functionspec.Parse("export.yaml", data, functionspec.Options{Vars: map[string]string{"API_TOKEN": "from-var"}})
+	if err != nil {
+		t.Fatalf("parsing the export: %v\n%s", err, data)
+	}
+	got := spec.Functions[0]
+	if got.ContainerArgs != fn.ContainerArgs {
+		t.Errorf("containerArgs = %q, want %q", got.ContainerArgs, fn.ContainerArgs)
+	}
+	want := []functionspec.EnvVar{{Key: "API_TOKEN", Value: "from-var"}, {Key: "AUTHOR", Value: "jane"}}
+	if len(got.ContainerEnvironment) != len(want) {
+		t.Fatalf("containerEnvironment = %v, want %v", got.ContainerEnvironment, want)
+	}
+	for i := range want {
+		if got.ContainerEnvironment[i] != want[i] {
+			t.Errorf("containerEnvironment[%d] = %v, want %v", i, got.ContainerEnvironment[i], want[i])
+		}
+	}
+}
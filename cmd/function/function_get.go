@@ -1,34 +1,48 @@
 package function
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
+	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 	"github.com/tmc/nvcf-go"
+	"gopkg.in/yaml.v3"
 )
 
 func functionGetCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "get [identifier]",
-		Args:    cobra.MaximumNArgs(1),
-		Short:   "Get details about a single function and its versions",
-		Long:    "Get details about a single function and its versions or deployments. The identifier can be a function name, function ID, or version ID. If no identifier is provided, all functions will be listed.",
-		Example: "nvcf function get myFunction\nnvcf function get fid123\nnvcf function get --name myFunction\nnvcf function get --function-id fid123 --version-id vid456",
-		RunE:    runFunctionGet,
+		Use:   "get [identifier]",
+		Args:  cobra.MaximumNArgs(1),
+		Short: "Get details about a single function and its versions",
+		Long: `Get details about a single function and its versions or deployments. The identifier can be a function name, function ID, or version ID. If no identifier is provided, all functions will be listed, as a table or in the format set by -o.
+
+Every matching version is shown with its container, environment, models, health check, tags, active instances and, when it is deployed, its deployment. Values of environment variables that look like secrets are masked, and the names of the secrets are left out, unless --include-secrets is set. With --json or -o yaml, the matching versions are printed as a list.`,
+		Example:      "nvcf function get myFunction\nnvcf function get fid123\nnvcf function get --name myFunction\nnvcf function get --function-id fid123 --version-id vid456\nnvcf function get fid123 -o yaml",
+		SilenceUsage: true,
+		RunE:         runFunctionGet,
 	}
 	cmd.Flags().String("name", "", "Filter by function name")
 	cmd.Flags().String("function-id", "", "Filter by function ID")
 	cmd.Flags().String("version-id", "", "Filter by version ID")
 	cmd.Flags().Bool("include-secrets", false, "Include secrets in the response")
+	cmd.Flags().StringP("output", "o", "", "Output format: json or yaml. Default is a human-readable view")
 	return cmd
 }
 
 func runFunctionGet(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	client := newClient(cmd)
 	includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+	format, err := getOutputFormat(cmd)
+	if err != nil {
+		return err
+	}
 
 	name, _ := cmd.Flags().GetString("name")
 	functionID, _ := cmd.Flags().GetString("function-id")
@@ -40,7 +54,7 @@ func runFunctionGet(cmd *cobra.Command, args []string) error {
 	}
 
 	if identifier == "" && name == "" && functionID == "" && versionID == "" {
-		return listAllFunctions(cmd, client)
+		return listAllFunctions(cmd, client, format)
 	}
 
 	functions, err := client.Functions.List(cmd.Context(), nvcf.FunctionListParams{})
@@ -48,28 +62,57 @@ func runFunctionGet(cmd *cobra.Command, args []string) error {
 		return output.Error(cmd, "Error listing functions", err)
 	}
 
-	matchedFunctions := []nvcf.ListFunctionsResponseFunction{}
+	details := []functionDetail{}
 	for _, fn := range functions.Functions {
 		if matchesIdentifier(fn, identifier, name, functionID, versionID) {
-			query := nvcf.FunctionVersionGetParams{
-				IncludeSecrets: nvcf.Bool(includeSecrets),
-			}
-			_, err := client.Functions.Versions.Get(cmd.Context(), fn.ID, fn.VersionID, query)
+			detail, err := getFunctionDetail(cmd, client, fn.ID, fn.VersionID, includeSecrets)
 			if err != nil {
-				return output.Error(cmd, fmt.Sprintf("Error getting function %s", fn.ID), err)
+				return err
 			}
-			matchedFunctions = append(matchedFunctions, fn)
+			details = append(details, detail)
 		}
 	}
 
-	if len(matchedFunctions) == 0 {
+	if len(details) == 0 {
 		return output.Error(cmd, "No matching functions found", nil)
 	}
 
-	output.Functions(cmd, matchedFunctions)
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			return output.Error(cmd, "Error formatting JSON", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	case "yaml":
+		return writeYAML(cmd, details)
+	default:
+		for i, detail := range details {
+			if i > 0 {
+				fmt.Fprintln(cmd.OutOrStdout())
+			}
+			printFunctionDetail(cmd.OutOrStdout(), detail)
+		}
+	}
 	return nil
 }
 
+// getOutputFormat is the format set by -o, or json with --json
+func getOutputFormat(cmd *cobra.Command) (string, error) {
+	format, _ := cmd.Flags().GetString("output")
+	format = strings.ToLower(format)
+	asJSON, _ := cmd.Flags().GetBool("json")
+	switch {
+	case format != "" && format != "json" && format != "yaml":
+		return "", output.Error(cmd, fmt.Sprintf("Invalid output format: '%s'. Use json or yaml", format), nil)
+	case asJSON && format == "yaml":
+		return "", output.Error(cmd, "--json cannot be combined with -o yaml", nil)
+	case asJSON:
+		return "json", nil
+	}
+	return format, nil
+}
+
 func matchesIdentifier(fn nvcf.ListFunctionsResponseFunction, identifier, name, functionID, versionID string) bool {
 	return (identifier != "" && (fn.Name == identifier || fn.ID == identifier || fn.VersionID == identifier)) ||
 		(name != "" && fn.Name == name) ||
@@ -77,11 +120,348 @@ func matchesIdentifier(fn nvcf.ListFunctionsResponseFunction, identifier, name,
 		(versionID != "" && fn.VersionID == versionID)
 }
 
-func listAllFunctions(cmd *cobra.Command, client *api.Client) error {
+// listAllFunctions prints every function version as a table, or as a list in format
+func listAllFunctions(cmd *cobra.Command, client *api.Client, format string) error {
 	functions, err := client.Functions.List(cmd.Context(), nvcf.FunctionListParams{})
 	if err != nil {
 		return output.Error(cmd, "Error listing functions", err)
 	}
-	output.Functions(cmd, functions.Functions)
+	switch format {
+	case "json":
+		data, err := json.MarshalIndent(functions.Functions, "", "  ")
+		if err != nil {
+			return output.Error(cmd, "Error formatting JSON", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	case "yaml":
+		// the API types only name their fields for JSON, so the YAML is made from it
+		data, err := json.Marshal(functions.Functions)
+		if err != nil {
+			return output.Error(cmd, "Error formatting YAML", err)
+		}
+		var list []any
+		if err := json.Unmarshal(data, &list); err != nil {
+			return output.Error(cmd, "Error formatting YAML", err)
+		}
+		return writeYAML(cmd, list)
+	default:
+		output.Functions(cmd, functions.Functions)
+	}
 	return nil
 }
+
+func writeYAML(cmd *cobra.Command, value any) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(value); err != nil {
+		return output.Error(cmd, "Error formatting YAML", err)
+	}
+	_ = encoder.Close()
+	_, err := cmd.OutOrStdout().Write(buf.Bytes())
+	return err
+}
+
+// functionDetail is everything 'function get' shows about a version
+type functionDetail struct {
+	ID                      string            `json:"id" yaml:"id"`
+	VersionID               string            `json:"versionId" yaml:"versionId"`
+	Name                    string            `json:"name" yaml:"name"`
+	Description             string            `json:"description,omitempty" yaml:"description,omitempty"`
+	Status                  string            `json:"status" yaml:"status"`
+	FunctionType            string            `json:"functionType,omitempty" yaml:"functionType,omitempty"`
+	APIBodyFormat           string            `json:"apiBodyFormat,omitempty" yaml:"apiBodyFormat,omitempty"`
+	NcaID                   string            `json:"ncaId,omitempty" yaml:"ncaId,omitempty"`
+	OwnedByDifferentAccount bool              `json:"ownedByDifferentAccount,omitempty" yaml:"ownedByDifferentAccount,omitempty"`
+	CreatedAt               time.Time         `json:"createdAt" yaml:"createdAt"`
+	ContainerImage          string            `json:"containerImage,omitempty" yaml:"containerImage,omitempty"`
+	ContainerArgs           string            `json:"containerArgs,omitempty" yaml:"containerArgs,omitempty"`
+	HelmChart               string            `json:"helmChart,omitempty" yaml:"helmChart,omitempty"`
+	HelmChartServiceName    string            `json:"helmChartServiceName,omitempty" yaml:"helmChartServiceName,omitempty"`
+	InferenceURL            string            `json:"inferenceUrl,omitempty" yaml:"inferenceUrl,omitempty"`
+	InferencePort           int64             `json:"inferencePort,omitempty" yaml:"inferencePort,omitempty"`
+	ContainerEnvironment    []envDetail       `json:"containerEnvironment,omitempty" yaml:"containerEnvironment,omitempty"`
+	Secrets                 []string          `json:"secrets,omitempty" yaml:"secrets,omitempty"`
+	Models                  []artifactDetail  `json:"models,omitempty" yaml:"models,omitempty"`
+	Resources               []artifactDetail  `json:"resources,omitempty" yaml:"resources,omitempty"`
+	Health                  healthDetail      `json:"health" yaml:"health"`
+	Tags                    []string          `json:"tags,omitempty" yaml:"tags,omitempty"`
+	ActiveInstances         []instanceDetail  `json:"activeInstances,omitempty" yaml:"activeInstances,omitempty"`
+	Deployment              *deploymentDetail `json:"deployment,omitempty" yaml:"deployment,omitempty"`
+}
+
+type envDetail struct {
+	Key   string `json:"key" yaml:"key"`
+	Value string `json:"value" yaml:"value"`
+}
+
+// artifactDetail is a model or resource of a version
+type artifactDetail struct {
+	Name    string `json:"name" yaml:"name"`
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	Uri     string `json:"uri,omitempty" yaml:"uri,omitempty"`
+}
+
+type healthDetail struct {
+	Protocol           string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	Uri                string `json:"uri,omitempty" yaml:"uri,omitempty"`
+	Port               int64  `json:"port,omitempty" yaml:"port,omitempty"`
+	Timeout            string `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	ExpectedStatusCode int64  `json:"expectedStatusCode,omitempty" yaml:"expectedStatusCode,omitempty"`
+}
+
+type instanceDetail struct {
+	InstanceID   string     `json:"instanceId" yaml:"instanceId"`
+	Status       string     `json:"status" yaml:"status"`
+	GPU          string     `json:"gpu,omitempty" yaml:"gpu,omitempty"`
+	InstanceType string     `json:"instanceType,omitempty" yaml:"instanceType,omitempty"`
+	Backend      string     `json:"backend,omitempty" yaml:"backend,omitempty"`
+	Location     string     `json:"location,omitempty" yaml:"location,omitempty"`
+	CreatedAt    *time.Time `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	UpdatedAt    *time.Time `json:"updatedAt,omitempty" yaml:"updatedAt,omitempty"`
+}
+
+type deploymentDetail struct {
+	Status          string                 `json:"status" yaml:"status"`
+	CreatedAt       time.Time              `json:"createdAt" yaml:"createdAt"`
+	RequestQueueURL string                 `json:"requestQueueUrl,omitempty" yaml:"requestQueueUrl,omitempty"`
+	Specifications  []deploymentSpecDetail `json:"specifications" yaml:"specifications"`
+}
+
+type deploymentSpecDetail struct {
+	GPU                   string   `json:"gpu" yaml:"gpu"`
+	InstanceType          string   `json:"instanceType" yaml:"instanceType"`
+	Backend               string   `json:"backend,omitempty" yaml:"backend,omitempty"`
+	MinInstances          int64    `json:"minInstances" yaml:"minInstances"`
+	MaxInstances          int64    `json:"maxInstances" yaml:"maxInstances"`
+	MaxRequestConcurrency int64    `json:"maxRequestConcurrency,omitempty" yaml:"maxRequestConcurrency,omitempty"`
+	Regions               []string `json:"regions,omitempty" yaml:"regions,omitempty"`
+	Clusters              []string `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	AvailabilityZones     []string `json:"availabilityZones,omitempty" yaml:"availabilityZones,omitempty"`
+	Attributes            []string `json:"attributes,omitempty" yaml:"attributes,omitempty"`
+	PreferredOrder        int64    `json:"preferredOrder,omitempty" yaml:"preferredOrder,omitempty"`
+	Configuration         any      `json:"configuration,omitempty" yaml:"configuration,omitempty"`
+}
+
+// getFunctionDetail reads a version and, when it is deployed, its deployment
+func getFunctionDetail(cmd *cobra.Command, client *api.Client, functionID, versionID string, includeSecrets bool) (functionDetail, error) {
+	version, err := client.Functions.Versions.Get(cmd.Context(), functionID, versionID, nvcf.FunctionVersionGetParams{
+		IncludeSecrets: nvcf.Bool(includeSecrets),
+	})
+	if err != nil {
+		return functionDetail{}, output.Error(cmd, fmt.Sprintf("Error getting function %s", functionID), err)
+	}
+	detail := functionDetailFromResponse(version.Function, includeSecrets)
+	if version.Function.Status == nvcf.FunctionResponseFunctionStatusInactive {
+		return detail, nil
+	}
+	deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, versionID)
+	if err != nil {
+		return functionDetail{}, output.Error(cmd, fmt.Sprintf("Error getting the deployment of function %s version %s", functionID, versionID), err)
+	}
+	detail.Deployment = &deploymentDetail{
+		Status:          string(deployment.Deployment.FunctionStatus),
+		CreatedAt:       deployment.Deployment.CreatedAt,
+		RequestQueueURL: deployment.Deployment.RequestQueueURL,
+	}
+	for _, spec := range deployment.Deployment.DeploymentSpecifications {
+		detail.Deployment.Specifications = append(detail.Deployment.Specifications, deploymentSpecDetail{
+			GPU:                   spec.GPU,
+			InstanceType:          spec.InstanceType,
+			Backend:               spec.Backend,
+			MinInstances:          spec.MinInstances,
+			MaxInstances:          spec.MaxInstances,
+			MaxRequestConcurrency: spec.MaxRequestConcurrency,
+			Regions:               spec.Regions,
+			Clusters:              spec.Clusters,
+			AvailabilityZones:     spec.AvailabilityZones,
+			Attributes:            spec.Attributes,
+			PreferredOrder:        spec.PreferredOrder,
+			Configuration:         spec.Configuration,
+		})
+	}
+	return detail, nil
+}
+
+// functionDetailFromResponse converts a version for display. Without
+// includeSecrets, secret names are left out and secret-looking values masked.
+func functionDetailFromResponse(fn nvcf.FunctionResponseFunction, includeSecrets bool) functionDetail {
+	detail := functionDetail{
+		ID:                      fn.ID,
+		VersionID:               fn.VersionID,
+		Name:                    fn.Name,
+		Description:             fn.Description,
+		Status:                  string(fn.Status),
+		FunctionType:            string(fn.FunctionType),
+		APIBodyFormat:           string(fn.APIBodyFormat),
+		NcaID:                   fn.NcaID,
+		OwnedByDifferentAccount: fn.OwnedByDifferentAccount,
+		CreatedAt:               fn.CreatedAt,
+		ContainerImage:          fn.ContainerImage,
+		ContainerArgs:           fn.ContainerArgs,
+		HelmChart:               fn.HelmChart,
+		HelmChartServiceName:    fn.HelmChartServiceName,
+		InferenceURL:            fn.InferenceURL,
+		InferencePort:           fn.InferencePort,
+		Tags:                    fn.Tags,
+		Health: healthDetail{
+			Protocol:           string(fn.Health.Protocol),
+			Uri:                fn.Health.Uri,
+			Port:               fn.Health.Port,
+			Timeout:            fn.Health.Timeout,
+			ExpectedStatusCode: fn.Health.ExpectedStatusCode,
+		},
+	}
+	if detail.Health.Uri == "" {
+		detail.Health.Uri = fn.HealthUri
+	}
+	for _, env := range fn.ContainerEnvironment {
+		value := env.Value
+		if !includeSecrets && isSensitiveEnvKey(env.Key) {
+			value = redactedValue
+		}
+		detail.ContainerEnvironment = append(detail.ContainerEnvironment, envDetail{Key: env.Key, Value: value})
+	}
+	if includeSecrets {
+		detail.Secrets = fn.Secrets
+	}
+	for _, model := range fn.Models {
+		detail.Models = append(detail.Models, artifactDetail{Name: model.Name, Version: model.Version, Uri: model.Uri})
+	}
+	for _, resource := range fn.Resources {
+		detail.Resources = append(detail.Resources, artifactDetail{Name: resource.Name, Version: resource.Version, Uri: resource.Uri})
+	}
+	for _, instance := range fn.ActiveInstances {
+		detail.ActiveInstances = append(detail.ActiveInstances, instanceDetail{
+			InstanceID:   instance.InstanceID,
+			Status:       string(instance.InstanceStatus),
+			GPU:          instance.GPU,
+			InstanceType: instance.InstanceType,
+			Backend:      instance.Backend,
+			Location:     instance.Location,
+			CreatedAt:    nonZeroTime(instance.InstanceCreatedAt),
+			UpdatedAt:    nonZeroTime(instance.InstanceUpdatedAt),
+		})
+	}
+	return detail
+}
+
+func nonZeroTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// printFunctionDetail prints a version in sections, leaving out empty fields and sections
+func printFunctionDetail(out io.Writer, detail functionDetail) {
+	section := func(title string, fields [][2]string) {
+		var lines []string
+		for _, field := range fields {
+			if field[1] != "" {
+				lines = append(lines, fmt.Sprintf("  %-22s %s", field[0]+":", field[1]))
+			}
+		}
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintln(out, title)
+		fmt.Fprintln(out, strings.Join(lines, "\n"))
+	}
+
+	fmt.Fprintf(out, "%s (%s)\n", detail.Name, detail.Status)
+	owned := ""
+	if detail.OwnedByDifferentAccount {
+		owned = "yes"
+	}
+	section("Function", [][2]string{
+		{"ID", detail.ID},
+		{"Version ID", detail.VersionID},
+		{"Description", detail.Description},
+		{"Type", detail.FunctionType},
+		{"API body format", detail.APIBodyFormat},
+		{"NCA ID", detail.NcaID},
+		{"Other account", owned},
+		{"Created", formatTime(detail.CreatedAt)},
+		{"Tags", strings.Join(detail.Tags, ", ")},
+	})
+	section("Container", [][2]string{
+		{"Image", detail.ContainerImage},
+		{"Args", detail.ContainerArgs},
+		{"Helm chart", detail.HelmChart},
+		{"Helm service", detail.HelmChartServiceName},
+		{"Inference URL", detail.InferenceURL},
+		{"Inference port", formatNonZero(detail.InferencePort)},
+	})
+	var env [][2]string
+	for _, variable := range detail.ContainerEnvironment {
+		env = append(env, [2]string{variable.Key, variable.Value})
+	}
+	section("Environment", env)
+	if len(detail.Secrets) > 0 {
+		fmt.Fprintln(out, "Secrets")
+		fmt.Fprintln(out, "  "+strings.Join(detail.Secrets, ", "))
+	}
+	var artifacts [][2]string
+	for _, model := range detail.Models {
+		artifacts = append(artifacts, [2]string{"model " + model.Name, joinNonEmpty(model.Version, model.Uri)})
+	}
+	for _, resource := range detail.Resources {
+		artifacts = append(artifacts, [2]string{"resource " + resource.Name, joinNonEmpty(resource.Version, resource.Uri)})
+	}
+	section("Models", artifacts)
+	section("Health", [][2]string{
+		{"Protocol", detail.Health.Protocol},
+		{"URI", detail.Health.Uri},
+		{"Port", formatNonZero(detail.Health.Port)},
+		{"Timeout", detail.Health.Timeout},
+		{"Expected status code", formatNonZero(detail.Health.ExpectedStatusCode)},
+	})
+
+	if detail.Deployment != nil {
+		section("Deployment", [][2]string{
+			{"Status", detail.Deployment.Status},
+			{"Created", formatTime(detail.Deployment.CreatedAt)},
+			{"Request queue URL", detail.Deployment.RequestQueueURL},
+		})
+		if len(detail.Deployment.Specifications) > 0 {
+			table := tablewriter.NewWriter(out)
+			table.SetHeader([]string{"GPU", "Instance Type", "Backend", "Instances", "Max Concurrency", "Regions", "Clusters"})
+			table.SetBorder(false)
+			table.SetAutoWrapText(false)
+			for _, spec := range detail.Deployment.Specifications {
+				table.Append([]string{spec.GPU, spec.InstanceType, spec.Backend,
+					fmt.Sprintf("%d-%d", spec.MinInstances, spec.MaxInstances), formatNonZero(spec.MaxRequestConcurrency),
+					strings.Join(spec.Regions, ", "), strings.Join(spec.Clusters, ", ")})
+			}
+			table.Render()
+		}
+	}
+
+	if len(detail.ActiveInstances) > 0 {
+		fmt.Fprintln(out, "Active instances")
+		table := tablewriter.NewWriter(out)
+		table.SetHeader([]string{"Instance ID", "Status", "GPU", "Instance Type", "Backend", "Location", "Created", "Updated"})
+		table.SetBorder(false)
+		table.SetAutoWrapText(false)
+		for _, instance := range detail.ActiveInstances {
+			table.Append([]string{instance.InstanceID, instance.Status, instance.GPU, instance.InstanceType,
+				instance.Backend, instance.Location, formatTimePtr(instance.CreatedAt), formatTimePtr(instance.UpdatedAt)})
+		}
+		table.Render()
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
@@ -0,0 +1,53 @@
+package function
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestListAllFunctionsFormats(t *testing.T) {
+	fake := newFakeAPI(t)
+	fake.created = 2
+
+	tests := []struct {
+		format    string
+		unmarshal func([]byte, any) error
+	}{
+		{format: "json", unmarshal: json.Unmarshal},
+		{format: "yaml", unmarshal: yaml.Unmarshal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			cmd := newTestCommand()
+			cmd.SetContext(context.Background())
+			cmd.SetOut(&out)
+			if err := listAllFunctions(cmd, fake.client(), tt.format); err != nil {
+				t.Fatal(err)
+			}
+			var functions []map[string]any
+			if err := tt.unmarshal(out.Bytes(), &functions); err != nil {
+				t.Fatalf("got output that is not %s: %v\n%s", tt.format, err, out.String())
+			}
+			if len(functions) != 2 || functions[0]["id"] != "fid-1" || functions[1]["versionId"] != "vid-2" || functions[1]["name"] != "fn-2" {
+				t.Errorf("got %v, want both functions", functions)
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	cmd := newTestCommand()
+	cmd.SetContext(context.Background())
+	cmd.SetOut(&out)
+	if err := listAllFunctions(cmd, fake.client(), ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "fn-1") || !strings.Contains(out.String(), "STATUS") {
+		t.Errorf("got\n%s\nwant a table", out.String())
+	}
+}
@@ -3,8 +3,6 @@ package function
 import (
 	"fmt"
 
-	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
 	"github.com/spf13/cobra"
 	"github.com/tmc/nvcf-go"
@@ -25,7 +23,7 @@ func functionListCmd() *cobra.Command {
 }
 
 func runFunctionList(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	client := newClient(cmd)
 	visibilityParams, err := parseVisibilityFlags(cmd)
 	if err != nil {
 		return err
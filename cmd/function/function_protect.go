@@ -0,0 +1,75 @@
+package function
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/collections"
+	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// protectedTag on any version of a function protects the whole function
+const protectedTag = "nvcf:protected"
+
+// checkProtection refuses destructive operations on a function that is tagged
+// nvcf:protected or listed in protected_functions, unless --override-protection is set
+func checkProtection(cmd *cobra.Command, functionID string, versions []nvcf.ListFunctionsResponseFunction) error {
+	reason := protectionReason(functionID, versions)
+	if reason == "" {
+		return nil
+	}
+	if override, _ := cmd.Flags().GetBool("override-protection"); override {
+		output.Info(cmd, fmt.Sprintf("Function %s is protected because %s. Proceeding because of --override-protection", functionID, reason))
+		return nil
+	}
+	return output.Error(cmd, fmt.Sprintf("Function %s is protected because %s. Pass --override-protection to %s it anyway", functionID, reason, cmd.Name()), nil)
+}
+
+// protectionReason explains why a function is protected, or is empty if it is not
+func protectionReason(functionID string, versions []nvcf.ListFunctionsResponseFunction) string {
+	if config.IsProtectedFunction(functionID) {
+		return "it is listed in protected_functions in ~/.nvcf/config.json"
+	}
+	for _, version := range versions {
+		if collections.ListContains(version.Tags, protectedTag) {
+			return fmt.Sprintf("version %s is tagged %s", version.VersionID, protectedTag)
+		}
+	}
+	return ""
+}
+
+// confirmAffected lists the versions an operation affects, with the deployments of
+// those that are deployed, and asks for confirmation
+func confirmAffected(cmd *cobra.Command, client *api.Client, functionID, action string, versions []nvcf.ListFunctionsResponseFunction) (bool, error) {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true, nil
+	}
+	lines := []string{fmt.Sprintf("This will %s %d version(s) of function %s:", action, len(versions), functionID)}
+	for _, version := range versions {
+		lines = append(lines, fmt.Sprintf("  %s  %-9s  %s  created %s", version.VersionID, version.Status, version.Name, version.CreatedAt.Format(time.RFC3339)))
+		if version.Status == nvcf.ListFunctionsResponseFunctionsStatusInactive {
+			continue
+		}
+		deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, version.VersionID)
+		if err != nil {
+			lines = append(lines, "    deployment: could not be read")
+			continue
+		}
+		for _, spec := range deployment.Deployment.DeploymentSpecifications {
+			lines = append(lines, fmt.Sprintf("    deployment: %s %s on %s, %d to %d instance(s)",
+				spec.GPU, spec.InstanceType, spec.Backend, spec.MinInstances, spec.MaxInstances))
+		}
+	}
+	return output.Confirm(cmd, strings.Join(lines, "\n"))
+}
+
+// addConfirmationFlags adds --yes and --override-protection to a destructive command
+func addConfirmationFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Do not ask for confirmation")
+	cmd.Flags().Bool("override-protection", false, "Act on functions protected by the nvcf:protected tag or protected_functions in ~/.nvcf/config.json")
+}
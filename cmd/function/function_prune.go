@@ -0,0 +1,193 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brevdev/nvcf/collections"
+	"github.com/brevdev/nvcf/flagutil"
+	"github.com/brevdev/nvcf/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// what prune does with a version
+const (
+	pruneKeep   = "keep"
+	pruneDelete = "delete"
+)
+
+func functionPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune [function-id]",
+		Short: "Delete old INACTIVE versions of a function",
+		Long: `Delete the INACTIVE versions of a function beyond the newest --keep, or of every private
+function with --all. The newest --keep versions are kept whatever their status, and versions
+that are not INACTIVE are never deleted. With --older-than, only versions created longer ago
+than that age are deleted.
+
+Functions protected by the nvcf:protected tag or protected_functions in ~/.nvcf/config.json are
+left alone unless --override-protection is set. The versions to delete are listed for
+confirmation unless --yes is set, and --dry-run lists what would be kept and deleted, and why,
+without deleting anything.`,
+		Example: `nvcf function prune fid --keep 5
+nvcf function prune --all --keep 3 --older-than 30d --dry-run`,
+		Args: cobra.RangeArgs(0, 1),
+		RunE: runFunctionPrune,
+	}
+	cmd.Flags().Int("keep", 0, "Number of newest versions of each function to keep (required)")
+	cmd.Flags().BoolP("all", "a", false, "Prune every private function")
+	cmd.Flags().String("older-than", "", "Only delete versions created longer ago than this age, such as 30d, 2w or 12h")
+	cmd.Flags().Int("parallel", 4, "How many versions to delete at once")
+	addConfirmationFlags(cmd)
+	_ = cmd.MarkFlagRequired("keep")
+	return cmd
+}
+
+// pruneDecision is what prune does with a version, and why
+type pruneDecision struct {
+	FunctionID string    `json:"functionId"`
+	VersionID  string    `json:"versionId"`
+	Name       string    `json:"name"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Action     string    `json:"action"`
+	Reason     string    `json:"reason"`
+
+	version nvcf.ListFunctionsResponseFunction
+}
+
+func runFunctionPrune(cmd *cobra.Command, args []string) error {
+	all, _ := cmd.Flags().GetBool("all")
+	if len(args) == 0 && !all {
+		return output.Error(cmd, "Pass a function ID, or --all to prune every private function", nil)
+	}
+	if len(args) > 0 && all {
+		return output.Error(cmd, "--all cannot be combined with a function ID", nil)
+	}
+	keep, _ := cmd.Flags().GetInt("keep")
+	if keep < 0 {
+		return output.Error(cmd, "--keep must not be negative", nil)
+	}
+	var olderThan time.Duration
+	if value, _ := cmd.Flags().GetString("older-than"); value != "" {
+		age, err := flagutil.ParseAge(value)
+		if err != nil {
+			return output.Error(cmd, err.Error(), nil)
+		}
+		olderThan = age
+	}
+
+	client := newClient(cmd)
+	listed, _, err := selectVersions(cmd.Context(), client, strings.Join(args, ""), versionSelector{})
+	if err != nil {
+		return output.Error(cmd, "Error listing function versions", err)
+	}
+
+	override, _ := cmd.Flags().GetBool("override-protection")
+	plan := planPrune(listed, keep, olderThan, time.Now())
+	var toDelete []nvcf.ListFunctionsResponseFunction
+	for i := range plan {
+		decision := &plan[i]
+		if decision.Action != pruneDelete {
+			continue
+		}
+		versions := collections.Filter(listed, func(v nvcf.ListFunctionsResponseFunction) bool {
+			return v.ID == decision.FunctionID
+		})
+		if reason := protectionReason(decision.FunctionID, versions); reason != "" && !override {
+			decision.Action, decision.Reason = pruneKeep, "the function is protected because "+reason
+			continue
+		}
+		toDelete = append(toDelete, decision.version)
+	}
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+		printPrunePlan(cmd, plan)
+		return nil
+	}
+	if len(toDelete) == 0 {
+		output.Info(cmd, fmt.Sprintf("No version is beyond retention, keeping all %d version(s)", len(plan)))
+		return nil
+	}
+	output.Info(cmd, fmt.Sprintf("Keeping %d version(s), deleting %d", len(plan)-len(toDelete), len(toDelete)))
+	return runBulkVersions(cmd, "delete", "deleted", toDelete, listed, func(ctx context.Context, version nvcf.ListFunctionsResponseFunction) error {
+		return client.Functions.Versions.Delete(ctx, version.ID, version.VersionID)
+	})
+}
+
+// planPrune decides which versions to keep and delete. Per function, the newest
+// keep versions are kept, as are versions that are not INACTIVE and, with
+// olderThan, versions created more recently than that.
+func planPrune(versions []nvcf.ListFunctionsResponseFunction, keep int, olderThan time.Duration, now time.Time) []pruneDecision {
+	byFunction := map[string][]nvcf.ListFunctionsResponseFunction{}
+	var functionIDs []string
+	for _, version := range versions {
+		if _, ok := byFunction[version.ID]; !ok {
+			functionIDs = append(functionIDs, version.ID)
+		}
+		byFunction[version.ID] = append(byFunction[version.ID], version)
+	}
+
+	var plan []pruneDecision
+	for _, functionID := range functionIDs {
+		group := byFunction[functionID]
+		sort.SliceStable(group, func(i, j int) bool {
+			return group[i].CreatedAt.After(group[j].CreatedAt)
+		})
+		for i, version := range group {
+			decision := pruneDecision{
+				FunctionID: version.ID,
+				VersionID:  version.VersionID,
+				Name:       version.Name,
+				Status:     string(version.Status),
+				CreatedAt:  version.CreatedAt,
+				Action:     pruneKeep,
+				version:    version,
+			}
+			switch {
+			case i < keep:
+				decision.Reason = fmt.Sprintf("one of the newest %d", keep)
+			case version.Status != nvcf.ListFunctionsResponseFunctionsStatusInactive:
+				decision.Reason = fmt.Sprintf("it is %s", version.Status)
+			case olderThan > 0 && !version.CreatedAt.Before(now.Add(-olderThan)):
+				decision.Reason = "created more recently than --older-than"
+			default:
+				decision.Action, decision.Reason = pruneDelete, "INACTIVE and beyond retention"
+			}
+			plan = append(plan, decision)
+		}
+	}
+	return plan
+}
+
+// printPrunePlan lists what prune would do with every version, for --dry-run
+func printPrunePlan(cmd *cobra.Command, plan []pruneDecision) {
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err == nil {
+			fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		}
+		return
+	}
+
+	deleting := 0
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Name", "Function ID", "Version ID", "Status", "Created", "Action", "Reason"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, decision := range plan {
+		if decision.Action == pruneDelete {
+			deleting++
+		}
+		table.Append([]string{decision.Name, decision.FunctionID, decision.VersionID, decision.Status,
+			decision.CreatedAt.Format(time.RFC3339), decision.Action, decision.Reason})
+	}
+	table.Render()
+	fmt.Fprintf(cmd.OutOrStdout(), "\nDry run: %d version(s) would be deleted and %d kept\n", deleting, len(plan)-deleting)
+}
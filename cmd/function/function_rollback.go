@@ -0,0 +1,200 @@
+package function
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/brevdev/nvcf/output"
+	"github.com/brevdev/nvcf/state"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+func functionRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollback <function-id>",
+		Short: "Roll back to the previously deployed version",
+		Long: `Redeploy the previous version of a function and stop the current one.
+
+The current version is the most recently created version that is deployed. The previous
+version is the one most recently deployed before it according to the deployment history
+the CLI keeps under ~/.nvcf/state, or, without history, the version created just before it.
+The previous version is deployed with the specifications it last had, falling back to
+those of the current version, and the current version is only stopped once the previous
+one is ACTIVE. A previous version that is still deploying is waited for rather than
+deployed again, and rolling back to a version whose deployment failed (ERROR) asks for
+confirmation first, or --yes.`,
+		Example: `nvcf function rollback fid
+nvcf function rollback fid --to vid
+nvcf function rollback fid --to vid --yes`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFunctionRollback,
+	}
+	cmd.Flags().String("to", "", "The version to roll back to. Defaults to the previously deployed version")
+	cmd.Flags().String("version-id", "", "The version to roll back from. Defaults to the newest deployed version")
+	cmd.Flags().BoolP("yes", "y", false, "Roll back to a version whose deployment failed without asking for confirmation")
+	addWaitFlags(cmd)
+	return cmd
+}
+
+func runFunctionRollback(cmd *cobra.Command, args []string) error {
+	client := newClient(cmd)
+	functionID := args[0]
+	currentID, _ := cmd.Flags().GetString("version-id")
+	targetID, _ := cmd.Flags().GetString("to")
+
+	versions, err := client.Functions.Versions.List(cmd.Context(), functionID)
+	if err != nil {
+		return output.Error(cmd, "Error listing function versions", err)
+	}
+	if currentID == "" {
+		current, ok := newestDeployedVersion(versions.Functions)
+		if !ok {
+			return output.Error(cmd, fmt.Sprintf("Function %s has no deployed version to roll back from", functionID), nil)
+		}
+		currentID = current.VersionID
+	}
+	current, ok := findVersion(versions.Functions, currentID)
+	if !ok {
+		return output.Error(cmd, fmt.Sprintf("Version %s not found in function %s", currentID, functionID), nil)
+	}
+
+	history, err := state.History(functionID)
+	if err != nil {
+		output.Info(cmd, fmt.Sprintf("Could not read the deployment history (%v); inferring the previous version from creation times", err))
+	}
+	if targetID == "" {
+		target, ok := previousVersion(versions.Functions, history, current)
+		if !ok {
+			return output.Error(cmd, fmt.Sprintf("Function %s has no version to roll back to", functionID), nil)
+		}
+		targetID = target.VersionID
+	}
+	target, ok := findVersion(versions.Functions, targetID)
+	if !ok {
+		return output.Error(cmd, fmt.Sprintf("Version %s not found in function %s", targetID, functionID), nil)
+	}
+	if targetID == currentID {
+		return output.Error(cmd, fmt.Sprintf("Version %s is already the current version", targetID), nil)
+	}
+
+	if target.Status == nvcf.ListFunctionsResponseFunctionsStatusError {
+		confirmed, err := output.Confirm(cmd, fmt.Sprintf("The last deployment of version %s failed (ERROR). Rolling back redeploys it and stops version %s once it is active.", targetID, currentID))
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			output.Info(cmd, "Rollback cancelled")
+			return nil
+		}
+	}
+
+	output.Info(cmd, fmt.Sprintf("Rolling back function %s from version %s to version %s", functionID, currentID, targetID))
+	switch target.Status {
+	case nvcf.ListFunctionsResponseFunctionsStatusActive:
+		output.Info(cmd, fmt.Sprintf("Version %s is still active", targetID))
+	case nvcf.ListFunctionsResponseFunctionsStatusDeploying:
+		// deploying it again would fail; wait for the deployment in progress instead
+		output.Info(cmd, fmt.Sprintf("Version %s is already deploying; waiting for it to become active", targetID))
+		if err := watchDeployment(cmd.Context(), cmd, client, functionID, targetID, waitOptionsFromFlags(cmd)); err != nil {
+			return output.Error(cmd, fmt.Sprintf("Version %s did not become active (%v). Version %s keeps serving", targetID, err, currentID), nil)
+		}
+		output.Success(cmd, fmt.Sprintf("Version %s is active", targetID))
+	default:
+		specs, source := rollbackSpecs(history, targetID)
+		if len(specs) == 0 {
+			deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, currentID)
+			if err != nil {
+				return output.Error(cmd, fmt.Sprintf("No deployment of version %s is recorded, and the deployment of version %s could not be read to reuse it", targetID, currentID), err)
+			}
+			specs, source = deployment.Deployment.DeploymentSpecifications, fmt.Sprintf("the deployment of version %s", currentID)
+		}
+		output.Info(cmd, fmt.Sprintf("Deploying version %s with %s", targetID, source))
+		for _, spec := range specs {
+			output.Info(cmd, fmt.Sprintf("  --backend %s --gpu %s --instance-type %s --min-instances %d --max-instances %d --max-request-concurrency %d",
+				spec.Backend, spec.GPU, spec.InstanceType, spec.MinInstances, spec.MaxInstances, spec.MaxRequestConcurrency))
+		}
+
+		_, err := client.FunctionDeployment.Functions.Versions.InitiateDeployment(cmd.Context(), functionID, targetID, nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams{
+			DeploymentSpecifications: nvcf.F(initiateDeploymentSpecs(specs)),
+		})
+		if err != nil {
+			return output.Error(cmd, fmt.Sprintf("Error deploying version %s. Version %s keeps serving", targetID, currentID), err)
+		}
+
+		if err := watchDeployment(cmd.Context(), cmd, client, functionID, targetID, waitOptionsFromFlags(cmd)); err != nil {
+			return output.Error(cmd, fmt.Sprintf("Version %s did not become active (%v). Version %s keeps serving; stop the failed deployment with 'nvcf function stop %s --version-id %s'",
+				targetID, err, currentID, functionID, targetID), nil)
+		}
+		output.Success(cmd, fmt.Sprintf("Version %s is active", targetID))
+	}
+
+	_, err = client.FunctionDeployment.Functions.Versions.DeleteDeployment(cmd.Context(), functionID, currentID, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
+		Graceful: nvcf.Bool(true),
+	})
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Version %s is serving, but version %s could not be stopped. Stop it with 'nvcf function stop %s --version-id %s'", targetID, currentID, functionID, currentID), err)
+	}
+	output.Success(cmd, fmt.Sprintf("Rolled back function %s: version %s is serving and version %s is stopped", functionID, targetID, currentID))
+	return nil
+}
+
+// rollbackSpecs returns the specifications a version was last deployed with and where they come from
+func rollbackSpecs(history []state.Event, versionID string) ([]nvcf.DeploymentResponseDeploymentDeploymentSpecification, string) {
+	event, ok := state.LastDeployment(history, versionID)
+	if !ok || len(event.Specifications) == 0 {
+		return nil, ""
+	}
+	return event.Specifications, fmt.Sprintf("its deployment of %s", event.At.Local().Format(time.RFC1123))
+}
+
+// previousVersion returns the version deployed before current according to the
+// history. Without history, it is the newest version created before current.
+func previousVersion(versions []nvcf.ListFunctionsResponseFunction, history []state.Event, current nvcf.ListFunctionsResponseFunction) (nvcf.ListFunctionsResponseFunction, bool) {
+	for i := len(history) - 1; i >= 0; i-- {
+		event := history[i]
+		if event.Action != state.ActionDeployed || event.VersionID == current.VersionID {
+			continue
+		}
+		if version, ok := findVersion(versions, event.VersionID); ok {
+			return version, true
+		}
+	}
+
+	sorted := make([]nvcf.ListFunctionsResponseFunction, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	for _, version := range sorted {
+		if version.VersionID != current.VersionID && version.CreatedAt.Before(current.CreatedAt) {
+			return version, true
+		}
+	}
+	return nvcf.ListFunctionsResponseFunction{}, false
+}
+
+// newestDeployedVersion returns the most recently created version that is active, deploying or failed to deploy
+func newestDeployedVersion(versions []nvcf.ListFunctionsResponseFunction) (nvcf.ListFunctionsResponseFunction, bool) {
+	var newest nvcf.ListFunctionsResponseFunction
+	found := false
+	for _, version := range versions {
+		switch version.Status {
+		case nvcf.ListFunctionsResponseFunctionsStatusActive, nvcf.ListFunctionsResponseFunctionsStatusDeploying, nvcf.ListFunctionsResponseFunctionsStatusError:
+			if !found || version.CreatedAt.After(newest.CreatedAt) {
+				newest, found = version, true
+			}
+		}
+	}
+	return newest, found
+}
+
+func findVersion(versions []nvcf.ListFunctionsResponseFunction, versionID string) (nvcf.ListFunctionsResponseFunction, bool) {
+	for _, version := range versions {
+		if version.VersionID == versionID {
+			return version, true
+		}
+	}
+	return nvcf.ListFunctionsResponseFunction{}, false
+}
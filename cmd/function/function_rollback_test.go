@@ -0,0 +1,45 @@
+package function
+
+import (
+	"testing"
+	"time"
+
+	"github.com/brevdev/nvcf/state"
+	"github.com/tmc/nvcf-go"
+)
+
+func TestPreviousVersion(t *testing.T) {
+	created := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	version := func(id string, day int) nvcf.ListFunctionsResponseFunction {
+		return nvcf.ListFunctionsResponseFunction{VersionID: id, CreatedAt: created.AddDate(0, 0, day)}
+	}
+	versions := []nvcf.ListFunctionsResponseFunction{version("v1", 0), version("v3", 2), version("v2", 1), version("v4", 3)}
+	deployed := func(id string) state.Event { return state.Event{VersionID: id, Action: state.ActionDeployed} }
+	stopped := func(id string) state.Event { return state.Event{VersionID: id, Action: state.ActionStopped} }
+
+	tests := []struct {
+		name    string
+		history []state.Event
+		current string
+		want    string
+		wantOK  bool
+	}{
+		{name: "without history, the newest version created before current", current: "v4", want: "v3", wantOK: true},
+		{name: "without history, versions created after current are skipped", current: "v2", want: "v1", wantOK: true},
+		{name: "without history, the oldest version has no previous version", current: "v1"},
+		{name: "the version deployed before current", history: []state.Event{deployed("v1"), deployed("v4")}, current: "v4", want: "v1", wantOK: true},
+		{name: "stops are not deployments", history: []state.Event{deployed("v2"), stopped("v3"), deployed("v4")}, current: "v4", want: "v2", wantOK: true},
+		{name: "the last deployment wins, even of a newer version", history: []state.Event{deployed("v1"), deployed("v4"), deployed("v2")}, current: "v2", want: "v4", wantOK: true},
+		{name: "deleted versions are skipped", history: []state.Event{deployed("v1"), deployed("v9"), deployed("v4")}, current: "v4", want: "v1", wantOK: true},
+		{name: "history of the current version only falls back to creation times", history: []state.Event{deployed("v4"), stopped("v4"), deployed("v4")}, current: "v4", want: "v3", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, _ := findVersion(versions, tt.current)
+			got, ok := previousVersion(versions, tt.history, current)
+			if ok != tt.wantOK || got.VersionID != tt.want {
+				t.Errorf("got %q, %v, want %q, %v", got.VersionID, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+func functionRolloutCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rollout <function-id>",
+		Short: "Roll out a new image to a deployed function",
+		Long: `Roll out a new container image with a blue/green deployment. The active version is
+cloned with the new image and any other overrides, and the clone is deployed with the
+same deployment specifications. Once it is ACTIVE, a smoke invocation is sent to it. If
+the smoke invocation passes, the previous version is stopped; if anything fails, the new
+version is torn down and the previous version keeps serving, also when the rollout is
+interrupted.
+
+The new version keeps the resources and secrets of the current one. The API returns only
+the names of secrets, never their values: pass every value with --secret, or the rollout
+is refused.`,
+		Example: `nvcf function rollout fid --image nvcr.io/myorg/myimage:1.2.0 --smoke-payload '{"prompt": "ping"}'
+nvcf function rollout fid --image nvcr.io/myorg/myimage:1.2.0 --smoke-payload-file smoke.json --smoke-expect '"status":"ok"'
+nvcf function rollout fid --version-id vid --image nvcr.io/myorg/myimage:1.2.0 --env LOG_LEVEL=debug --skip-smoke
+nvcf function rollout fid --image nvcr.io/myorg/myimage:1.2.0 --secret HF_TOKEN=hf_xxx --skip-smoke`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFunctionRollout,
+	}
+	cmd.Flags().String("version-id", "", "The version to roll out from. Defaults to the newest active version")
+	cmd.Flags().String("image", "", "Container image for the new version (required)")
+	cmd.Flags().String("container-args", "", "Container arguments for the new version. Defaults to those of the current version")
+	cmd.Flags().StringSlice("env", []string{}, "Environment variables to set on the new version, on top of those of the current version (format: KEY=VALUE, key:value, or KEY to pass through the local value)")
+	cmd.Flags().String("env-file", "", "Dotenv file with environment variables to set on the new version. --env values override it")
+	cmd.Flags().StringArray("secret", nil, "Value of a secret of the current version, format: NAME=VALUE (can be used multiple times)")
+	cmd.Flags().String("smoke-payload", "", "JSON body of the smoke invocation sent to the new version")
+	cmd.Flags().String("smoke-payload-file", "", "File with the JSON body of the smoke invocation")
+	cmd.Flags().String("smoke-expect", "", "Text the smoke invocation response must contain. By default any successful response passes")
+	cmd.Flags().Duration("smoke-timeout", 2*time.Minute, "How long to wait for the smoke invocation")
+	cmd.Flags().Bool("skip-smoke", false, "Cut over as soon as the new version is ACTIVE, without a smoke invocation")
+	addWaitFlags(cmd)
+	_ = cmd.MarkFlagRequired("image")
+	return cmd
+}
+
+// smokeTest is the invocation that verifies a new version before cutover
+type smokeTest struct {
+	Payload json.RawMessage
+	Expect  string
+	Timeout time.Duration
+}
+
+func runFunctionRollout(cmd *cobra.Command, args []string) error {
+	client := newClient(cmd)
+	functionID := args[0]
+	versionID, _ := cmd.Flags().GetString("version-id")
+	image, _ := cmd.Flags().GetString("image")
+
+	smoke, err := smokeTestFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	secrets, err := parseKeyValues(cmd, "secret")
+	if err != nil {
+		return err
+	}
+
+	if versionID == "" {
+		versions, err := client.Functions.Versions.List(cmd.Context(), functionID)
+		if err != nil {
+			return output.Error(cmd, "Error listing function versions", err)
+		}
+		active, ok := newestActiveVersion(versions.Functions)
+		if !ok {
+			return output.Error(cmd, fmt.Sprintf("Function %s has no active version to roll out from. Use 'nvcf function deploy' to deploy it first", functionID), nil)
+		}
+		versionID = active.VersionID
+	}
+
+	current, err := client.Functions.Versions.Get(cmd.Context(), functionID, versionID, nvcf.FunctionVersionGetParams{
+		IncludeSecrets: nvcf.Bool(true),
+	})
+	if err != nil {
+		output.Info(cmd, "Could not read the version with its secrets; reading it without them")
+		current, err = client.Functions.Versions.Get(cmd.Context(), functionID, versionID, nvcf.FunctionVersionGetParams{
+			IncludeSecrets: nvcf.Bool(false),
+		})
+	}
+	if err != nil {
+		return output.Error(cmd, "Error getting function version", err)
+	}
+	if current.Function.Status != nvcf.FunctionResponseFunctionStatusActive {
+		return output.Error(cmd, fmt.Sprintf("Version %s is %s. Roll out from an active version", versionID, current.Function.Status), nil)
+	}
+	deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, versionID)
+	if err != nil {
+		return output.Error(cmd, "Error getting the deployment of the current version", err)
+	}
+
+	var secretParams []nvcf.FunctionVersionNewParamsSecret
+	var missingSecrets []string
+	for _, name := range current.Function.Secrets {
+		value, ok := secrets[name]
+		if !ok {
+			missingSecrets = append(missingSecrets, name)
+			continue
+		}
+		secretParams = append(secretParams, nvcf.FunctionVersionNewParamsSecret{Name: nvcf.F(name), Value: nvcf.F(value)})
+	}
+	if len(missingSecrets) > 0 {
+		return output.Error(cmd, fmt.Sprintf("Version %s has secrets the new version would lose. Pass their values with --secret NAME=VALUE: %s", versionID, strings.Join(missingSecrets, ", ")), nil)
+	}
+
+	clone := current.Function
+	clone.ContainerImage = image
+	if cmd.Flags().Changed("container-args") {
+		clone.ContainerArgs, _ = cmd.Flags().GetString("container-args")
+	}
+	envFile, _ := cmd.Flags().GetString("env-file")
+	envVars, _ := cmd.Flags().GetStringSlice("env")
+	overrides, err := readEnvFlags(cmd, envFile, envVars)
+	if err != nil {
+		return err
+	}
+	clone.ContainerEnvironment = mergeResponseEnv(clone.ContainerEnvironment, overrides)
+
+	// from here on an interrupt tears down the new version instead of leaving it behind
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	output.Info(cmd, fmt.Sprintf("Rolling out %s to function %s (current version %s, image %s)", image, functionID, versionID, current.Function.ContainerImage))
+	newVersionID, err := createNewVersion(cmd, client, clone, secretParams)
+	if err != nil {
+		return err
+	}
+	output.Success(cmd, fmt.Sprintf("Created version %s", newVersionID))
+	failed := func(deployed bool, message string, cause error) error {
+		if ctx.Err() != nil && cmd.Context().Err() == nil {
+			// a second interrupt exits right away
+			stop()
+			message, cause = "Rollout interrupted", ctx.Err()
+		}
+		return rolloutFailed(cmd, client, functionID, newVersionID, deployed, message, cause)
+	}
+	if ctx.Err() != nil {
+		return failed(false, "Rollout interrupted", ctx.Err())
+	}
+
+	_, err = client.FunctionDeployment.Functions.Versions.InitiateDeployment(ctx, functionID, newVersionID, nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParams{
+		DeploymentSpecifications: nvcf.F(initiateDeploymentSpecs(deployment.Deployment.DeploymentSpecifications)),
+	})
+	if err != nil {
+		return failed(false, "Error deploying the new version", err)
+	}
+
+	if err := watchDeployment(ctx, cmd, client, functionID, newVersionID, waitOptionsFromFlags(cmd)); err != nil {
+		return failed(true, "The new version did not become active", err)
+	}
+	output.Success(cmd, fmt.Sprintf("Version %s is active", newVersionID))
+
+	if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun && smoke != nil {
+		_, _ = client.InvokeVersion(ctx, functionID, newVersionID, smoke.Payload)
+		output.Info(cmd, "Dry run: the smoke invocation is not evaluated")
+	} else if smoke != nil {
+		output.Info(cmd, "Sending the smoke invocation...")
+		if err := runSmokeTest(ctx, client, functionID, newVersionID, *smoke); err != nil {
+			return failed(true, "The smoke invocation failed", err)
+		}
+		output.Success(cmd, "Smoke invocation passed")
+	}
+	if ctx.Err() != nil {
+		return failed(true, "Rollout interrupted", ctx.Err())
+	}
+	stop()
+
+	_, err = client.FunctionDeployment.Functions.Versions.DeleteDeployment(cmd.Context(), functionID, versionID, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
+		Graceful: nvcf.Bool(true),
+	})
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Version %s is serving, but the previous version %s could not be stopped. Stop it with 'nvcf function stop %s --version-id %s'", newVersionID, versionID, functionID, versionID), err)
+	}
+	output.Success(cmd, fmt.Sprintf("Rolled out %s: version %s is serving and version %s is stopped", image, newVersionID, versionID))
+	return nil
+}
+
+// smokeTestFromFlags returns the smoke invocation asked for, or nil with --skip-smoke
+func smokeTestFromFlags(cmd *cobra.Command) (*smokeTest, error) {
+	skip, _ := cmd.Flags().GetBool("skip-smoke")
+	payload, _ := cmd.Flags().GetString("smoke-payload")
+	payloadFile, _ := cmd.Flags().GetString("smoke-payload-file")
+	expect, _ := cmd.Flags().GetString("smoke-expect")
+	smokeTimeout, _ := cmd.Flags().GetDuration("smoke-timeout")
+
+	switch {
+	case skip:
+		return nil, nil
+	case payload != "" && payloadFile != "":
+		return nil, output.Error(cmd, "Use either --smoke-payload or --smoke-payload-file, not both", nil)
+	case payloadFile != "":
+		data, err := os.ReadFile(payloadFile)
+		if err != nil {
+			return nil, output.Error(cmd, fmt.Sprintf("Error reading smoke payload file %s", payloadFile), err)
+		}
+		payload = string(data)
+	case payload == "":
+		return nil, output.Error(cmd, "A rollout needs a smoke invocation to verify the new version. Set --smoke-payload or --smoke-payload-file, or --skip-smoke to cut over without one", nil)
+	}
+	if !json.Valid([]byte(payload)) {
+		return nil, output.Error(cmd, "The smoke payload is not valid JSON", nil)
+	}
+	return &smokeTest{Payload: json.RawMessage(payload), Expect: expect, Timeout: smokeTimeout}, nil
+}
+
+// runSmokeTest invokes the version and checks that it answers successfully with the expected text
+func runSmokeTest(ctx context.Context, client *api.Client, functionID, versionID string, smoke smokeTest) error {
+	ctx, cancel := context.WithTimeout(ctx, smoke.Timeout)
+	defer cancel()
+	invocation, err := client.InvokeVersion(ctx, functionID, versionID, smoke.Payload)
+	if err != nil {
+		return err
+	}
+	if smoke.Expect != "" && !strings.Contains(string(invocation.Body), smoke.Expect) {
+		return fmt.Errorf("the response does not contain %q: %s", smoke.Expect, truncate(string(invocation.Body), 500))
+	}
+	return nil
+}
+
+// rolloutFailed tears down the new version and reports why the rollout stopped.
+// The teardown runs on its own context so that it also runs after an interrupt.
+func rolloutFailed(cmd *cobra.Command, client *api.Client, functionID, versionID string, deployed bool, message string, cause error) error {
+	output.Info(cmd, fmt.Sprintf("%s: %v", message, cause))
+	output.Info(cmd, fmt.Sprintf("Tearing down version %s. The previous version keeps serving.", versionID))
+	ctx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+	defer cancel()
+	if err := teardownVersion(ctx, client, functionID, versionID, deployed); err != nil {
+		return output.Error(cmd, fmt.Sprintf("%s, and version %s could not be torn down (%v). Remove it with 'nvcf function stop %s --version-id %s' and 'nvcf function delete %s --version-id %s'",
+			message, versionID, err, functionID, versionID, functionID, versionID), nil)
+	}
+	return output.Error(cmd, fmt.Sprintf("%s. Rollout aborted and version %s removed", message, versionID), nil)
+}
+
+// teardownVersion stops the deployment of a version, if it was deployed, waits
+// for its instances to be gone and deletes it
+func teardownVersion(ctx context.Context, client *api.Client, functionID, versionID string, deployed bool) error {
+	if deployed {
+		err := stopDeployment(ctx, client, functionID, versionID, stopOptions{
+			Wait:        true,
+			waitOptions: waitOptions{Timeout: rollbackStopTimeout, PollInterval: defaultPollInterval},
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return client.Functions.Versions.Delete(ctx, functionID, versionID)
+}
+
+// newestActiveVersion returns the most recently created ACTIVE version
+func newestActiveVersion(versions []nvcf.ListFunctionsResponseFunction) (nvcf.ListFunctionsResponseFunction, bool) {
+	sorted := make([]nvcf.ListFunctionsResponseFunction, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	for _, version := range sorted {
+		if version.Status == nvcf.ListFunctionsResponseFunctionsStatusActive {
+			return version, true
+		}
+	}
+	return nvcf.ListFunctionsResponseFunction{}, false
+}
+
+// initiateDeploymentSpecs turns the specifications of an existing deployment into
+// the parameters that deploy another version the same way
+func initiateDeploymentSpecs(specs []nvcf.DeploymentResponseDeploymentDeploymentSpecification) []nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification {
+	var params []nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification
+	for _, spec := range specs {
+		param := nvcf.FunctionDeploymentFunctionVersionInitiateDeploymentParamsDeploymentSpecification{
+			GPU:                   nvcf.String(spec.GPU),
+			InstanceType:          nvcf.String(spec.InstanceType),
+			Backend:               nvcf.String(spec.Backend),
+			MaxInstances:          nvcf.Int(spec.MaxInstances),
+			MinInstances:          nvcf.Int(spec.MinInstances),
+			MaxRequestConcurrency: nvcf.Int(spec.MaxRequestConcurrency),
+		}
+		if len(spec.Attributes) > 0 {
+			param.Attributes = nvcf.F(spec.Attributes)
+		}
+		if len(spec.AvailabilityZones) > 0 {
+			param.AvailabilityZones = nvcf.F(spec.AvailabilityZones)
+		}
+		if len(spec.Clusters) > 0 {
+			param.Clusters = nvcf.F(spec.Clusters)
+		}
+		if len(spec.Regions) > 0 {
+			param.Regions = nvcf.F(spec.Regions)
+		}
+		if spec.Configuration != nil {
+			param.Configuration = nvcf.F(spec.Configuration)
+		}
+		if spec.PreferredOrder != 0 {
+			param.PreferredOrder = nvcf.Int(spec.PreferredOrder)
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// mergeResponseEnv sets overrides on top of the environment of a function version
+func mergeResponseEnv(env []nvcf.FunctionResponseFunctionContainerEnvironment, overrides []functionspec.EnvVar) []nvcf.FunctionResponseFunctionContainerEnvironment {
+	current := make([]functionspec.EnvVar, 0, len(env))
+	for _, envVar := range env {
+		current = append(current, functionspec.EnvVar{Key: envVar.Key, Value: envVar.Value})
+	}
+	var merged []nvcf.FunctionResponseFunctionContainerEnvironment
+	for _, envVar := range functionspec.MergeEnv(current, overrides) {
+		merged = append(merged, nvcf.FunctionResponseFunctionContainerEnvironment{Key: envVar.Key, Value: envVar.Value})
+	}
+	return merged
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,287 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/output"
+	"github.com/brevdev/nvcf/schedule"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// scheduleWakeInterval is the longest the schedule sleeps before checking the
+// clock again, so that boundaries passed while the machine was asleep are noticed
+const scheduleWakeInterval = time.Minute
+
+func functionScheduleCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedule",
+		Short: "Scale deployments on a schedule",
+		Long: `Scale the min and max instances of deployments on a schedule.
+
+A schedule file maps cron expressions to instance targets:
+
+  timezone: America/Los_Angeles   # defaults to the local timezone
+  schedules:
+    - functionId: fid
+      versionId: vid              # defaults to every ACTIVE version
+      gpu: L40                    # optional, only scale specifications with this GPU
+      instanceType: gl40_1.br20_2xlarge  # optional
+      windows:
+        - cron: "0 8 * * 1-5"     # weekdays from 8:00
+          minInstances: 2
+          maxInstances: 8
+        - cron: "0 20 * * 1-5"    # weekdays from 20:00
+          minInstances: 0
+          maxInstances: 2
+
+Each window applies from when its cron expression matches until another window of
+the same schedule matches. Only the min and max instances of a deployment change.`,
+	}
+	cmd.AddCommand(functionScheduleRunCmd())
+	return cmd
+}
+
+func functionScheduleRunCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <schedule-file>",
+		Short: "Apply a scaling schedule, in the foreground",
+		Long: `Apply a scaling schedule until interrupted. Every deployment is scaled to the window in
+effect right away and again at each window boundary, and every change is logged.
+
+The window in effect is the one whose cron expression matched most recently, so
+boundaries missed while the schedule was not running, or the machine was asleep, are
+caught up on. With --once, the windows in effect are applied a single time, for
+running from cron or a CI job instead.`,
+		Example: `nvcf function schedule run schedule.yaml
+nvcf function schedule run schedule.yaml --once --dry-run`,
+		Args: cobra.ExactArgs(1),
+		RunE: runFunctionScheduleRun,
+	}
+	cmd.Flags().Bool("once", false, "Apply the windows in effect now and exit")
+	return cmd
+}
+
+func runFunctionScheduleRun(cmd *cobra.Command, args []string) error {
+	file, err := schedule.Load(args[0])
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("invalid schedule file:\n%v", err), nil)
+	}
+	client := newClient(cmd)
+	log := newScheduleLog(cmd)
+
+	if once, _ := cmd.Flags().GetBool("once"); once {
+		if failed := applySchedule(cmd.Context(), client, file, time.Now(), log); failed > 0 {
+			return fmt.Errorf("%d deployment(s) could not be scaled", failed)
+		}
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.info(fmt.Sprintf("Running schedule %s in %s. Press Ctrl-C to stop", args[0], file.Location()))
+	for {
+		applySchedule(ctx, client, file, time.Now(), log)
+		next := file.Next(time.Now())
+		if next.IsZero() {
+			log.info("No window starts in the next five years. Stopping")
+			return nil
+		}
+		log.info(fmt.Sprintf("Next boundary at %s", next.Format(time.RFC1123)))
+		for time.Now().Before(next) {
+			if err := sleepContext(ctx, min(time.Until(next), scheduleWakeInterval)); err != nil {
+				log.info("Stopped")
+				return nil
+			}
+		}
+	}
+}
+
+// applySchedule scales every deployment of the schedule to the window in effect
+// at now and returns how many could not be scaled
+func applySchedule(ctx context.Context, client *api.Client, file *schedule.File, now time.Time, log *scheduleLog) int {
+	failed := 0
+	for _, entry := range file.Schedules {
+		window, since, ok := entry.Active(now, file.Location())
+		if !ok {
+			log.info(fmt.Sprintf("%s: no window has started yet", entry))
+			continue
+		}
+
+		versionIDs := []string{entry.VersionID}
+		if entry.VersionID == "" {
+			versions, err := client.Functions.Versions.List(ctx, entry.FunctionID)
+			if err != nil {
+				log.failure(entry.FunctionID, "", fmt.Errorf("listing versions: %w", err))
+				failed++
+				continue
+			}
+			versionIDs = nil
+			for _, version := range versions.Functions {
+				if version.Status == nvcf.ListFunctionsResponseFunctionsStatusActive {
+					versionIDs = append(versionIDs, version.VersionID)
+				}
+			}
+			if len(versionIDs) == 0 {
+				log.info(fmt.Sprintf("%s: no version is ACTIVE", entry))
+			}
+		}
+
+		for _, versionID := range versionIDs {
+			if err := scaleDeployment(ctx, client, entry, versionID, window, since, log); err != nil {
+				log.failure(entry.FunctionID, versionID, err)
+				failed++
+			}
+		}
+	}
+	return failed
+}
+
+// scaleDeployment sets the min and max instances of the matching deployment
+// specifications of a version, keeping the rest of the deployment as it is
+func scaleDeployment(ctx context.Context, client *api.Client, entry schedule.Entry, versionID string, window schedule.Window, since time.Time, log *scheduleLog) error {
+	deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(ctx, entry.FunctionID, versionID)
+	if err != nil {
+		return fmt.Errorf("reading the deployment: %w", err)
+	}
+	if status := deployment.Deployment.FunctionStatus; status != nvcf.DeploymentResponseDeploymentFunctionStatusActive {
+		log.info(fmt.Sprintf("%s/%s: skipped, the deployment is %s", entry.FunctionID, versionID, status))
+		return nil
+	}
+
+	var params []nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParamsDeploymentSpecification
+	var changes []scheduleEvent
+	matched := false
+	for _, spec := range deployment.Deployment.DeploymentSpecifications {
+		param := nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParamsDeploymentSpecification{
+			GPU:                   nvcf.String(spec.GPU),
+			InstanceType:          nvcf.String(spec.InstanceType),
+			MinInstances:          nvcf.Int(spec.MinInstances),
+			MaxInstances:          nvcf.Int(spec.MaxInstances),
+			MaxRequestConcurrency: nvcf.Int(spec.MaxRequestConcurrency),
+		}
+		if (entry.GPU == "" || entry.GPU == spec.GPU) && (entry.InstanceType == "" || entry.InstanceType == spec.InstanceType) {
+			matched = true
+			if spec.MinInstances != window.MinInstances || spec.MaxInstances != window.MaxInstances {
+				param.MinInstances = nvcf.Int(window.MinInstances)
+				param.MaxInstances = nvcf.Int(window.MaxInstances)
+				changes = append(changes, scheduleEvent{
+					Event:        scheduleEventScaled,
+					FunctionID:   entry.FunctionID,
+					VersionID:    versionID,
+					GPU:          spec.GPU,
+					InstanceType: spec.InstanceType,
+					From:         &instanceRange{Min: spec.MinInstances, Max: spec.MaxInstances},
+					To:           &instanceRange{Min: window.MinInstances, Max: window.MaxInstances},
+					Window:       window.Cron,
+					Since:        &since,
+				})
+			}
+		}
+		params = append(params, param)
+	}
+	if !matched {
+		return fmt.Errorf("no deployment specification matches %s", joinNonEmpty(entry.GPU, entry.InstanceType))
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	_, err = client.FunctionDeployment.Functions.Versions.UpdateDeployment(ctx, entry.FunctionID, versionID, nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParams{
+		DeploymentSpecifications: nvcf.F(params),
+	})
+	if err != nil {
+		return fmt.Errorf("updating the deployment: %w", err)
+	}
+	for _, change := range changes {
+		log.emit(change)
+	}
+	return nil
+}
+
+// events of a schedule run
+const (
+	scheduleEventInfo   = "info"
+	scheduleEventScaled = "scaled"
+	scheduleEventError  = "error"
+)
+
+type instanceRange struct {
+	Min int64 `json:"minInstances"`
+	Max int64 `json:"maxInstances"`
+}
+
+// scheduleEvent is a line of the schedule log. With --json each event is
+// printed as a line of JSON.
+type scheduleEvent struct {
+	Time         time.Time      `json:"time"`
+	Event        string         `json:"event"`
+	FunctionID   string         `json:"functionId,omitempty"`
+	VersionID    string         `json:"versionId,omitempty"`
+	GPU          string         `json:"gpu,omitempty"`
+	InstanceType string         `json:"instanceType,omitempty"`
+	From         *instanceRange `json:"from,omitempty"`
+	To           *instanceRange `json:"to,omitempty"`
+	Window       string         `json:"window,omitempty"`
+	Since        *time.Time     `json:"since,omitempty"`
+	Message      string         `json:"message,omitempty"`
+}
+
+// scheduleLog prints timestamped schedule events. --quiet only leaves changes and errors.
+type scheduleLog struct {
+	cmd    *cobra.Command
+	asJSON bool
+	quiet  bool
+}
+
+func newScheduleLog(cmd *cobra.Command) *scheduleLog {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	return &scheduleLog{cmd: cmd, asJSON: asJSON, quiet: quiet}
+}
+
+func (l *scheduleLog) info(message string) {
+	if !l.quiet {
+		l.emit(scheduleEvent{Event: scheduleEventInfo, Message: message})
+	}
+}
+
+func (l *scheduleLog) failure(functionID, versionID string, err error) {
+	l.emit(scheduleEvent{Event: scheduleEventError, FunctionID: functionID, VersionID: versionID, Message: err.Error()})
+}
+
+func (l *scheduleLog) emit(event scheduleEvent) {
+	event.Time = time.Now()
+	out := l.cmd.OutOrStdout()
+	if l.asJSON {
+		event.Time = event.Time.UTC()
+		if data, err := json.Marshal(event); err == nil {
+			fmt.Fprintln(out, string(data))
+		}
+		return
+	}
+
+	var message string
+	switch event.Event {
+	case scheduleEventScaled:
+		spec := joinNonEmpty(event.GPU, event.InstanceType)
+		message = fmt.Sprintf("%s/%s %s: min %d -> %d, max %d -> %d (window %q since %s)",
+			event.FunctionID, event.VersionID, spec, event.From.Min, event.To.Min, event.From.Max, event.To.Max,
+			event.Window, event.Since.Format(time.RFC1123))
+	case scheduleEventError:
+		message = fmt.Sprintf("Error: %s %s: %s", event.FunctionID, event.VersionID, event.Message)
+	default:
+		message = event.Message
+	}
+	if event.Event == scheduleEventError {
+		out = l.cmd.ErrOrStderr()
+	}
+	fmt.Fprintf(out, "%s  %s\n", event.Time.Format(time.RFC3339), message)
+}
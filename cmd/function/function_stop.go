@@ -1,71 +1,259 @@
 package function
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/collections"
 	"github.com/brevdev/nvcf/output"
 	"github.com/spf13/cobra"
 	"github.com/tmc/nvcf-go"
 )
 
+const defaultDrainTimeout = 10 * time.Minute
+
 func functionStopCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "stop <function-id>",
-		Short:   "Stop a deployed function",
-		Long:    "Stop a deployed function. If a version-id is not provided, we look for versions that are actively deployed. If a single function is deployed, we stop that version. If multiple functions are deployed, we prompt for the version-id to stop.",
-		Example: "nvcf function stop fid --version-id vid",
-		Args:    cobra.ExactArgs(1),
-		RunE:    runFunctionStop,
+		Use:   "stop [function-id]",
+		Short: "Stop a deployed function",
+		Long: `Stop a deployed function. --version-id stops that version. Without it, we look for versions that are deployed, whether ACTIVE, DEPLOYING or ERROR: a single one is stopped, and with several we prompt for the version to stop, or --all stops them all.
+
+Instances are stopped right away, dropping the requests they are serving. With --graceful, they first finish their in-flight requests; instances still draining after --drain-timeout are force-stopped. Either way, stop waits until the instances are gone before it reports success, unless --detached is set.
+
+--all lists the deployments to be stopped for confirmation unless --yes is set, and functions protected by the nvcf:protected tag or protected_functions in ~/.nvcf/config.json are only stopped with --override-protection. --tag, --name, --status and --older-than select ACTIVE versions (or those with --status) of the function, or of all your functions without a function ID, and stop them a few at a time.`,
+		Example: `nvcf function stop fid --version-id vid
+nvcf function stop fid --all --graceful --drain-timeout 5m
+nvcf function stop --tag team=search
+nvcf function stop fid --status DEPLOYING --status ERROR --parallel 8`,
+		Args: cobra.RangeArgs(0, 1),
+		RunE: runFunctionStop,
 	}
 	cmd.Flags().Bool("all", false, "Stop all deployed versions of the function")
 	cmd.Flags().String("version-id", "", "The ID of the version")
-	cmd.Flags().Bool("force", false, "Gracefully stop the function if it's already deployed. If not, we forcefully stop the function.")
+	cmd.Flags().String("select", "", output.SelectFlagUsage)
+	cmd.Flags().Bool("graceful", false, "Let instances finish their in-flight requests before they stop")
+	cmd.Flags().Duration("drain-timeout", defaultDrainTimeout, "With --graceful, how long instances may drain before they are force-stopped")
+	cmd.Flags().BoolP("detached", "d", false, "Return once the stop is requested, without waiting for the instances to be gone")
+	cmd.Flags().Duration("wait-timeout", defaultWaitTimeout, "How long to wait for the instances to be gone")
+	cmd.Flags().Duration("poll-interval", defaultPollInterval, fmt.Sprintf("How often to check the instances. Checks slow down to every %s while nothing changes", maxPollInterval))
+	cmd.Flags().Bool("force", false, "Stop the function right away")
+	_ = cmd.Flags().MarkDeprecated("force", "stopping is immediate unless --graceful is set")
+	addConfirmationFlags(cmd)
+	addSelectorFlags(cmd, "ACTIVE")
 	return cmd
 }
 
+// stopOptions are how a deployment is stopped
+type stopOptions struct {
+	Graceful     bool
+	DrainTimeout time.Duration
+	// Wait for the instances to be gone
+	Wait bool
+	waitOptions
+}
+
+func stopOptionsFromFlags(cmd *cobra.Command) (stopOptions, error) {
+	opts := stopOptions{waitOptions: waitOptionsFromFlags(cmd)}
+	opts.Graceful, _ = cmd.Flags().GetBool("graceful")
+	opts.DrainTimeout, _ = cmd.Flags().GetDuration("drain-timeout")
+	if force, _ := cmd.Flags().GetBool("force"); force && opts.Graceful {
+		return opts, output.Error(cmd, "--force cannot be combined with --graceful", nil)
+	}
+	if cmd.Flags().Changed("drain-timeout") && !opts.Graceful {
+		return opts, output.Error(cmd, "--drain-timeout only applies with --graceful", nil)
+	}
+	if opts.DrainTimeout <= 0 {
+		return opts, output.Error(cmd, "--drain-timeout must be positive", nil)
+	}
+	detached, _ := cmd.Flags().GetBool("detached")
+	// a dry run does not stop anything, so there would be nothing to wait for
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	opts.Wait = !detached && !dryRun
+	return opts, nil
+}
+
 func runFunctionStop(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	selector, bulk, err := bulkMode(cmd, args, nvcf.ListFunctionsResponseFunctionsStatusActive)
+	if err != nil {
+		return err
+	}
+	opts, err := stopOptionsFromFlags(cmd)
+	if err != nil {
+		return err
+	}
+	client := newClient(cmd)
+	if bulk {
+		return runBulk(cmd, client, strings.Join(args, ""), "stop", "stopped", selector, func(ctx context.Context, version nvcf.ListFunctionsResponseFunction) error {
+			return stopDeployment(ctx, client, version.ID, version.VersionID, opts, nil)
+		})
+	}
 
 	functionId := args[0]
 	versionId, _ := cmd.Flags().GetString("version-id")
-	force, _ := cmd.Flags().GetBool("force")
 	all, _ := cmd.Flags().GetBool("all")
+	if all && versionId != "" {
+		return output.Error(cmd, "--all cannot be combined with --version-id", nil)
+	}
 
-	if versionId == "" {
-		versions, err := client.Functions.Versions.List(cmd.Context(), functionId)
+	versions, err := client.Functions.Versions.List(cmd.Context(), functionId)
+	if err != nil {
+		return output.Error(cmd, "Error listing function versions", err)
+	}
+	deployed := collections.Filter(versions.Functions, func(version nvcf.ListFunctionsResponseFunction) bool {
+		return version.Status != nvcf.ListFunctionsResponseFunctionsStatusInactive
+	})
+
+	var toStop []nvcf.ListFunctionsResponseFunction
+	switch {
+	case versionId != "":
+		version, ok := findVersion(versions.Functions, versionId)
+		if !ok {
+			return output.Error(cmd, fmt.Sprintf("Version %s not found in function %s", versionId, functionId), nil)
+		}
+		if version.Status == nvcf.ListFunctionsResponseFunctionsStatusInactive {
+			return output.Error(cmd, fmt.Sprintf("Version %s of function %s is not deployed", versionId, functionId), nil)
+		}
+		toStop = []nvcf.ListFunctionsResponseFunction{version}
+	case len(deployed) == 0:
+		return output.Error(cmd, "No versions of this function are currently deployed", nil)
+	case all:
+		toStop = deployed
+	default:
+		versionId, err = output.SelectVersion(cmd, deployed, "stop")
 		if err != nil {
-			return output.Error(cmd, "Error listing function versions", err)
+			return err
 		}
-		deployedVersionsToStop := []string{}
-		for _, version := range versions.Functions {
-			if version.Status == "ACTIVE" {
-				deployedVersionsToStop = append(deployedVersionsToStop, version.VersionID)
-			}
+		version, _ := findVersion(deployed, versionId)
+		toStop = []nvcf.ListFunctionsResponseFunction{version}
+	}
+
+	if err := checkProtection(cmd, functionId, versions.Functions); err != nil {
+		return err
+	}
+	if all {
+		confirmed, err := confirmAffected(cmd, client, functionId, "stop", toStop)
+		if err != nil {
+			return err
+		}
+		if !confirmed {
+			output.Info(cmd, "Nothing stopped")
+			return nil
+		}
+	}
+
+	for _, version := range toStop {
+		if err := stopVersion(cmd, client, functionId, version.VersionID, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopVersion stops one version, following the instances on a timeline until they are gone
+func stopVersion(cmd *cobra.Command, client *api.Client, functionID, versionID string, opts stopOptions) error {
+	how := "Stopping"
+	if opts.Graceful {
+		how = "Gracefully stopping"
+	}
+	output.Info(cmd, fmt.Sprintf("%s function %s version %s", how, functionID, versionID))
+
+	timeline := newDeploymentTimeline(cmd, functionID, versionID)
+	timeline.spinner.Suffix = fmt.Sprintf("  Waiting for the instances of version %s to stop...", versionID)
+	if opts.Wait {
+		timeline.start()
+	}
+	err := stopDeployment(cmd.Context(), client, functionID, versionID, opts, timeline.status)
+	timeline.stop()
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Error stopping function %s version %s: %v", functionID, versionID, err), nil)
+	}
+	if !opts.Wait {
+		output.Success(cmd, fmt.Sprintf("Stop requested for function %s version %s", functionID, versionID))
+		return nil
+	}
+	output.Success(cmd, fmt.Sprintf("Function %s version %s stopped successfully", functionID, versionID))
+	return nil
+}
+
+// stopDeployment stops the deployment of a version and, with opts.Wait, waits
+// until its instances are gone. Graceful stops that have not drained within
+// opts.DrainTimeout are escalated to a forced stop. onStatus, if set, is called
+// with the status and instance count of every poll.
+func stopDeployment(ctx context.Context, client *api.Client, functionID, versionID string, opts stopOptions, onStatus func(status string)) error {
+	_, err := client.FunctionDeployment.Functions.Versions.DeleteDeployment(ctx, functionID, versionID, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
+		Graceful: nvcf.Bool(opts.Graceful),
+	})
+	if err != nil {
+		return err
+	}
+	if !opts.Wait {
+		return nil
+	}
+
+	if opts.Graceful {
+		drainCtx, cancel := context.WithTimeout(ctx, opts.DrainTimeout)
+		err := waitForInstancesGone(drainCtx, client, functionID, versionID, opts.PollInterval, onStatus)
+		drained := drainCtx.Err() == nil
+		cancel()
+		if err == nil || ctx.Err() != nil || drained {
+			return err
 		}
-		if len(deployedVersionsToStop) == 0 {
-			return output.Error(cmd, "No functions are currently deployed", nil)
+		if onStatus != nil {
+			onStatus(fmt.Sprintf("still draining after %s, force-stopping", opts.DrainTimeout))
 		}
-		if all {
-			for _, version := range deployedVersionsToStop {
-				_, err = client.FunctionDeployment.Functions.Versions.DeleteDeployment(cmd.Context(), functionId, version, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
-					Graceful: nvcf.Bool(force),
-				})
-				if err != nil {
-					return output.Error(cmd, fmt.Sprintf("Error stopping function %s version %s", functionId, version), err)
-				}
-				output.Success(cmd, fmt.Sprintf("Function %s version %s stopped successfully", functionId, version))
+		_, err = client.FunctionDeployment.Functions.Versions.DeleteDeployment(ctx, functionID, versionID, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
+			Graceful: nvcf.Bool(false),
+		})
+		if err != nil {
+			return fmt.Errorf("force-stopping after the drain timeout: %w", err)
+		}
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	err = waitForInstancesGone(waitCtx, client, functionID, versionID, opts.PollInterval, onStatus)
+	if err != nil && ctx.Err() == nil && waitCtx.Err() != nil {
+		return fmt.Errorf("the instances were still running after %s", opts.Timeout)
+	}
+	return err
+}
+
+// waitForInstancesGone polls a version until it is INACTIVE without active
+// instances, or ctx is done. Polls back off while nothing changes.
+func waitForInstancesGone(ctx context.Context, client *api.Client, functionID, versionID string, pollInterval time.Duration, onStatus func(status string)) error {
+	interval := pollInterval
+	var last string
+	for {
+		version, err := client.Functions.Versions.Get(ctx, functionID, versionID, nvcf.FunctionVersionGetParams{
+			IncludeSecrets: nvcf.Bool(false),
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-		} else {
-			_, err := client.FunctionDeployment.Functions.Versions.DeleteDeployment(cmd.Context(), functionId, deployedVersionsToStop[0], nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
-				Graceful: nvcf.Bool(force),
-			})
-			if err != nil {
-				return output.Error(cmd, fmt.Sprintf("Error stopping function %s version %s", functionId, deployedVersionsToStop[0]), err)
+			return err
+		}
+		instances := len(version.Function.ActiveInstances)
+		if version.Function.Status == nvcf.FunctionResponseFunctionStatusInactive && instances == 0 {
+			if onStatus != nil {
+				onStatus(string(version.Function.Status))
 			}
-			output.Success(cmd, fmt.Sprintf("Function %s version %s stopped successfully", functionId, versionId))
+			return nil
+		}
+		status := fmt.Sprintf("%s, %d instance(s) left", version.Function.Status, instances)
+		if onStatus != nil {
+			onStatus(status)
+		}
+		if status == last {
+			interval = min(interval*3/2, max(pollInterval, maxPollInterval))
+		} else {
+			interval, last = pollInterval, status
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
 		}
 	}
-	return nil
 }
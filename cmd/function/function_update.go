@@ -1,13 +1,11 @@
 package function
 
 import (
-	"bufio"
 	"fmt"
-	"os"
+	"strconv"
 	"strings"
 
-	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/collections"
 	"github.com/brevdev/nvcf/output"
 	"github.com/spf13/cobra"
 	"github.com/tmc/nvcf-go"
@@ -30,16 +28,19 @@ func functionUpdateCmd() *cobra.Command {
 		RunE:    runFunctionUpdate,
 	}
 	cmd.Flags().String("version-id", "", "The ID of the version")
+	cmd.Flags().String("select", "", output.SelectFlagUsage)
+	cmd.Flags().Int("spec-index", 0, "Which deployment specification to update (1-based) when the version has several. Without it, you are prompted")
 	cmd.Flags().StringVar(&gpu, "gpu", "", "GPU name from the cluster")
 	cmd.Flags().StringVar(&instanceType, "instance-type", "", "Instance type, based on GPU, assigned to a Worker")
 	cmd.Flags().Int64Var(&minInstances, "min-instances", 0, "Minimum number of spot instances for the deployment")
 	cmd.Flags().Int64Var(&maxInstances, "max-instances", 0, "Maximum number of spot instances for the deployment")
 	cmd.Flags().Int64Var(&maxRequestConcurrency, "max-request-concurrency", 0, "Max request concurrency between 1 (default) and 1024")
+	addBudgetFlag(cmd)
 	return cmd
 }
 
 func runFunctionUpdate(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	client := newClient(cmd)
 	functionID := args[0]
 	versionID, _ := cmd.Flags().GetString("version-id")
 
@@ -75,29 +76,22 @@ func runFunctionUpdate(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	var targetDeployment nvcf.DeploymentResponseDeploymentDeploymentSpecification
 	// there can be multiple deployments for a version - prompt and check similar to how we do the version check
-	if len(fnDeployment.Deployment.DeploymentSpecifications) > 1 {
-		output.Info(cmd, "Multiple deployment specifications found. Please select one to update:")
-		for i, spec := range fnDeployment.Deployment.DeploymentSpecifications {
-			output.Info(cmd, fmt.Sprintf("[%d] GPU: %s, Instance Type: %s, Min Instances: %d, Max Instances: %d, Max Request Concurrency: %d",
-				i+1, spec.GPU, spec.InstanceType, spec.MinInstances, spec.MaxInstances, spec.MaxRequestConcurrency))
-		}
-
-		var selectedIndex int
-		for {
-			fmt.Print("Enter the number of the deployment specification to update: ")
-			_, err := fmt.Scanf("%d", &selectedIndex)
-			if err == nil && selectedIndex > 0 && selectedIndex <= len(fnDeployment.Deployment.DeploymentSpecifications) {
-				break
+	specs := fnDeployment.Deployment.DeploymentSpecifications
+	selectedIndex := 1
+	if len(specs) > 1 {
+		selectedIndex, _ = cmd.Flags().GetInt("spec-index")
+		if selectedIndex == 0 {
+			selectedIndex, err = selectDeploymentSpec(cmd, specs)
+			if err != nil {
+				return err
 			}
-			output.Info(cmd, "Invalid selection. Please try again.")
 		}
-
-		targetDeployment = fnDeployment.Deployment.DeploymentSpecifications[selectedIndex-1]
-	} else {
-		targetDeployment = fnDeployment.Deployment.DeploymentSpecifications[0]
+		if selectedIndex < 1 || selectedIndex > len(specs) {
+			return output.Error(cmd, fmt.Sprintf("--spec-index must be between 1 and %d", len(specs)), nil)
+		}
 	}
+	targetDeployment := specs[selectedIndex-1]
 
 	// build deployment spec with values from the targetDeployment
 	deploymentSpec := nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParamsDeploymentSpecification{
@@ -124,6 +118,26 @@ func runFunctionUpdate(cmd *cobra.Command, args []string) error {
 		deploymentSpec.MaxRequestConcurrency = nvcf.Int(maxRequestConcurrency)
 	}
 
+	// the version costs every one of its specifications, the updated one as it will be
+	deployments := make([]budgetedDeployment, 0, len(specs))
+	for i, spec := range specs {
+		deployment := budgetedDeployment{GPU: spec.GPU, InstanceType: spec.InstanceType, MaxInstances: spec.MaxInstances}
+		if i == selectedIndex-1 {
+			deployment = budgetedDeployment{
+				GPU:          deploymentSpec.GPU.Value,
+				InstanceType: deploymentSpec.InstanceType.Value,
+				MaxInstances: deploymentSpec.MaxInstances.Value,
+			}
+		}
+		if len(specs) > 1 {
+			deployment.Name = fmt.Sprintf("specification %d", i+1)
+		}
+		deployments = append(deployments, deployment)
+	}
+	if err := checkHourlyBudget(cmd, deployments...); err != nil {
+		return err
+	}
+
 	updateParams := nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParams{
 		DeploymentSpecifications: nvcf.F([]nvcf.FunctionDeploymentFunctionVersionUpdateDeploymentParamsDeploymentSpecification{deploymentSpec}),
 	}
@@ -139,21 +153,42 @@ func runFunctionUpdate(cmd *cobra.Command, args []string) error {
 }
 
 func selectVersionToUpdate(cmd *cobra.Command, versions []nvcf.ListFunctionsResponseFunction) (string, error) {
-	if len(versions) == 1 {
-		if versions[0].Status == "INACTIVE" {
-			return "", output.Error(cmd, "You can only update a deployed version. This version is inactive.", nil)
-		}
-		return versions[0].VersionID, nil
+	if len(versions) == 1 && versions[0].Status == "INACTIVE" {
+		return "", output.Error(cmd, "You can only update a deployed version. This version is inactive.", nil)
+	}
+	deployed := collections.Filter(versions, func(version nvcf.ListFunctionsResponseFunction) bool {
+		return version.Status != "INACTIVE"
+	})
+	if len(deployed) == 0 {
+		return "", output.Error(cmd, "You can only update a deployed version. No version of this function is deployed.", nil)
+	}
+	return output.SelectVersion(cmd, deployed, "update")
+}
+
+// selectDeploymentSpec asks which of several deployment specifications to update
+// and returns its 1-based index
+func selectDeploymentSpec(cmd *cobra.Command, specs []nvcf.DeploymentResponseDeploymentDeploymentSpecification) (int, error) {
+	var lines []string
+	for i, spec := range specs {
+		lines = append(lines, fmt.Sprintf("[%d] GPU: %s, Instance Type: %s, Min Instances: %d, Max Instances: %d, Max Request Concurrency: %d",
+			i+1, spec.GPU, spec.InstanceType, spec.MinInstances, spec.MaxInstances, spec.MaxRequestConcurrency))
+	}
+	if !output.CanPrompt(cmd) {
+		return 0, output.Error(cmd, fmt.Sprintf("Multiple deployment specifications found and input is not possible. Pass --spec-index to choose the one to update:\n%s", strings.Join(lines, "\n")), nil)
 	}
 
-	output.Info(cmd, "Multiple deployed versions found. Please select a version to update:")
-	for _, function := range versions {
-		if function.Status != "INACTIVE" {
-			output.Info(cmd, fmt.Sprintf("Version ID: %s || Status: %s", function.VersionID, function.Status))
+	output.Info(cmd, "Multiple deployment specifications found. Please select one to update:")
+	for _, line := range lines {
+		output.Info(cmd, line)
+	}
+	for {
+		answer, err := output.ReadLine("Enter the number of the deployment specification to update: ")
+		if err != nil {
+			return 0, output.Error(cmd, "No deployment specification selected", err)
+		}
+		if index, err := strconv.Atoi(answer); err == nil && index > 0 && index <= len(specs) {
+			return index, nil
 		}
+		output.Info(cmd, "Invalid selection. Please try again.")
 	}
-	reader := bufio.NewReader(os.Stdin)
-	fmt.Print("Enter version-id to update: ")
-	versionID, _ := reader.ReadString('\n')
-	return strings.TrimSpace(versionID), nil
 }
@@ -0,0 +1,87 @@
+package function
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brevdev/nvcf/cmd/gpu"
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+)
+
+// validateDeployment checks a deployment against the org's cluster groups and
+// capacity before anything is created, unless --skip-validation is set. If the
+// cluster groups cannot be read, it warns and lets the API decide.
+func validateDeployment(cmd *cobra.Command, backend, gpuType, instanceType string, maxInstances int64) error {
+	if skip, _ := cmd.Flags().GetBool("skip-validation"); skip {
+		return nil
+	}
+	err := gpu.ValidateDeployment(cmd.Context(), backend, gpuType, instanceType, maxInstances)
+	return deploymentValidationError(cmd, err)
+}
+
+// validateSpecDeployments checks the deployment of every function in a spec,
+// reading the org's cluster groups once. Functions deployed to the same backend,
+// GPU and instance type share its capacity, so their max instances are summed.
+func validateSpecDeployments(cmd *cobra.Command, functions []functionspec.FunctionDef) error {
+	if skip, _ := cmd.Flags().GetBool("skip-validation"); skip {
+		return nil
+	}
+	combinations, err := gpu.AvailableCombinations(cmd.Context())
+	if err != nil {
+		return deploymentValidationError(cmd, err)
+	}
+	var problems []string
+	for _, group := range groupByCombination(functions) {
+		err := gpu.ValidateCombination(combinations, group.Backend, group.GPU, group.InstanceType, group.MaxInstances)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", strings.Join(group.Names, ", "), err))
+		}
+	}
+	if len(problems) > 0 {
+		return output.Error(cmd, fmt.Sprintf("%s\nUse --skip-validation to deploy anyway.", strings.Join(problems, "\n")), nil)
+	}
+	return nil
+}
+
+// combinationDemand is the instances that the functions of a spec ask of one
+// backend, GPU and instance type
+type combinationDemand struct {
+	Backend      string
+	GPU          string
+	InstanceType string
+	MaxInstances int64
+	Names        []string
+}
+
+// groupByCombination sums the max instances of the functions per backend, GPU
+// and instance type, in the order the combinations first appear
+func groupByCombination(functions []functionspec.FunctionDef) []combinationDemand {
+	var groups []combinationDemand
+	index := map[[3]string]int{}
+	for _, fn := range functions {
+		key := [3]string{fn.InstBackend, fn.InstGPUType, fn.InstType}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, combinationDemand{Backend: fn.InstBackend, GPU: fn.InstGPUType, InstanceType: fn.InstType})
+		}
+		groups[i].MaxInstances += fn.InstMax
+		groups[i].Names = append(groups[i].Names, fn.FnName)
+	}
+	return groups
+}
+
+func deploymentValidationError(cmd *cobra.Command, err error) error {
+	var invalid *gpu.InvalidDeploymentError
+	switch {
+	case errors.As(err, &invalid):
+		return output.Error(cmd, fmt.Sprintf("%s\nUse --skip-validation to deploy anyway.", invalid), nil)
+	case err != nil:
+		output.Info(cmd, "Could not read the org's cluster groups to validate the deployment; deploying anyway")
+	}
+	return nil
+}
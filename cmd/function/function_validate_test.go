@@ -0,0 +1,23 @@
+package function
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/brevdev/nvcf/functionspec"
+)
+
+func TestGroupByCombination(t *testing.T) {
+	functions := []functionspec.FunctionDef{
+		{FnName: "echo", InstBackend: "GFN", InstGPUType: "L40", InstType: "gl40_1.br20_2xlarge", InstMax: 2},
+		{FnName: "embed", InstBackend: "GFN", InstGPUType: "T10", InstType: "g6.full", InstMax: 1},
+		{FnName: "chat", InstBackend: "GFN", InstGPUType: "L40", InstType: "gl40_1.br20_2xlarge", InstMax: 3},
+	}
+	want := []combinationDemand{
+		{Backend: "GFN", GPU: "L40", InstanceType: "gl40_1.br20_2xlarge", MaxInstances: 5, Names: []string{"echo", "chat"}},
+		{Backend: "GFN", GPU: "T10", InstanceType: "g6.full", MaxInstances: 1, Names: []string{"embed"}},
+	}
+	if got := groupByCombination(functions); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,312 @@
+package function
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/output"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+func functionVersionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "versions <function-id>",
+		Short: "List the versions of a function",
+		Long: `List the versions of a function, newest first, with when they were created, their status,
+image and, for versions that are deployed, their deployment.`,
+		Example: `nvcf function versions fid
+nvcf function versions diff fid vid1 vid2`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         runFunctionVersions,
+	}
+	cmd.AddCommand(functionVersionsDiffCmd())
+	return cmd
+}
+
+func functionVersionsDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff <function-id> <version-id> <version-id>",
+		Short: "Show what changed between two versions of a function",
+		Long: `Compare two versions of a function field by field: image, args, environment, secrets, models,
+health check and deployment. Only the fields that differ are shown. Values of environment
+variables that look like secrets are masked, and secret values are never shown.`,
+		Example:      "nvcf function versions diff fid vid1 vid2",
+		Args:         cobra.ExactArgs(3),
+		SilenceUsage: true,
+		RunE:         runFunctionVersionsDiff,
+	}
+	return cmd
+}
+
+// versionSummary is a line of 'function versions'
+type versionSummary struct {
+	VersionID   string    `json:"versionId"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	Image       string    `json:"image,omitempty"`
+	HelmChart   string    `json:"helmChart,omitempty"`
+	Deployments []string  `json:"deployments,omitempty"`
+}
+
+func runFunctionVersions(cmd *cobra.Command, args []string) error {
+	functionID := args[0]
+	client := newClient(cmd)
+	versions, err := client.Functions.Versions.List(cmd.Context(), functionID)
+	if err != nil {
+		return output.Error(cmd, "Error listing function versions", err)
+	}
+	sorted := versions.Functions
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	summaries := make([]versionSummary, 0, len(sorted))
+	for _, version := range sorted {
+		summary := versionSummary{
+			VersionID: version.VersionID,
+			Name:      version.Name,
+			Status:    string(version.Status),
+			CreatedAt: version.CreatedAt,
+			Image:     version.ContainerImage,
+			HelmChart: version.HelmChart,
+		}
+		if version.Status != nvcf.ListFunctionsResponseFunctionsStatusInactive {
+			deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, version.VersionID)
+			if err != nil {
+				summary.Deployments = []string{"could not be read"}
+			} else {
+				for _, spec := range deployment.Deployment.DeploymentSpecifications {
+					summary.Deployments = append(summary.Deployments, describeDeploymentSpec(spec))
+				}
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(summaries, "", "  ")
+		if err != nil {
+			return output.Error(cmd, "Error formatting JSON", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Created", "Version ID", "Name", "Status", "Image", "Deployment"})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, summary := range summaries {
+		image := summary.Image
+		if image == "" {
+			image = summary.HelmChart
+		}
+		table.Append([]string{summary.CreatedAt.Local().Format(time.RFC3339), summary.VersionID, summary.Name,
+			summary.Status, image, strings.Join(summary.Deployments, "; ")})
+	}
+	table.Render()
+	return nil
+}
+
+// describeDeploymentSpec summarizes a deployment spec, such as "L40 gl40_1.br20_2xlarge on GFN, 1-4 instances"
+func describeDeploymentSpec(spec nvcf.DeploymentResponseDeploymentDeploymentSpecification) string {
+	return fmt.Sprintf("%s on %s, %d-%d instances", joinNonEmpty(spec.GPU, spec.InstanceType), spec.Backend, spec.MinInstances, spec.MaxInstances)
+}
+
+// versionField is a field of a version as compared by 'versions diff'. Value is
+// what is compared, and Display what is shown, which masks secrets.
+type versionField struct {
+	Name    string
+	Value   string
+	Display string
+}
+
+// fieldChange is a field that differs between two versions. From and To are
+// empty where a version does not have the field.
+type fieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+func runFunctionVersionsDiff(cmd *cobra.Command, args []string) error {
+	functionID, fromID, toID := args[0], args[1], args[2]
+	client := newClient(cmd)
+	from, err := versionFields(cmd, client, functionID, fromID)
+	if err != nil {
+		return err
+	}
+	to, err := versionFields(cmd, client, functionID, toID)
+	if err != nil {
+		return err
+	}
+	changes := diffVersionFields(from, to)
+
+	if asJSON, _ := cmd.Flags().GetBool("json"); asJSON {
+		data, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			return output.Error(cmd, "Error formatting JSON", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+		return nil
+	}
+	if len(changes) == 0 {
+		output.Info(cmd, fmt.Sprintf("Versions %s and %s do not differ", fromID, toID))
+		return nil
+	}
+	table := tablewriter.NewWriter(cmd.OutOrStdout())
+	table.SetHeader([]string{"Field", fromID, toID})
+	table.SetBorder(false)
+	table.SetAutoWrapText(false)
+	for _, change := range changes {
+		table.Append([]string{change.Field, orNone(change.From), orNone(change.To)})
+	}
+	table.Render()
+	return nil
+}
+
+// versionFields reads a version and its deployment as a list of fields to compare
+func versionFields(cmd *cobra.Command, client *api.Client, functionID, versionID string) ([]versionField, error) {
+	version, err := client.Functions.Versions.Get(cmd.Context(), functionID, versionID, nvcf.FunctionVersionGetParams{
+		IncludeSecrets: nvcf.Bool(false),
+	})
+	if err != nil {
+		return nil, output.Error(cmd, fmt.Sprintf("Error getting version %s of function %s", versionID, functionID), err)
+	}
+	fn := version.Function
+
+	var fields []versionField
+	add := func(name, value string) {
+		fields = append(fields, versionField{Name: name, Value: value, Display: value})
+	}
+	add("name", fn.Name)
+	add("image", fn.ContainerImage)
+	add("args", fn.ContainerArgs)
+	add("helmChart", fn.HelmChart)
+	add("helmChartServiceName", fn.HelmChartServiceName)
+	add("inferenceUrl", fn.InferenceURL)
+	add("inferencePort", formatNonZero(fn.InferencePort))
+	add("apiBodyFormat", string(fn.APIBodyFormat))
+	add("functionType", string(fn.FunctionType))
+	add("description", fn.Description)
+	add("tags", strings.Join(sortedCopy(fn.Tags), ", "))
+	for _, env := range fn.ContainerEnvironment {
+		field := versionField{Name: "env." + env.Key, Value: env.Value, Display: env.Value}
+		if isSensitiveEnvKey(env.Key) {
+			field.Display = redactedValue
+		}
+		fields = append(fields, field)
+	}
+	add("secrets", strings.Join(sortedCopy(fn.Secrets), ", "))
+	for _, model := range fn.Models {
+		add("models."+model.Name, joinNonEmpty(model.Version, model.Uri))
+	}
+	for _, resource := range fn.Resources {
+		add("resources."+resource.Name, joinNonEmpty(resource.Version, resource.Uri))
+	}
+	healthUri := fn.Health.Uri
+	if healthUri == "" {
+		healthUri = fn.HealthUri
+	}
+	add("health.protocol", string(fn.Health.Protocol))
+	add("health.uri", healthUri)
+	add("health.port", formatNonZero(fn.Health.Port))
+	add("health.timeout", fn.Health.Timeout)
+	add("health.expectedStatusCode", formatNonZero(fn.Health.ExpectedStatusCode))
+
+	if fn.Status == nvcf.FunctionResponseFunctionStatusInactive {
+		return fields, nil
+	}
+	deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionID, versionID)
+	if err != nil {
+		return nil, output.Error(cmd, fmt.Sprintf("Error getting the deployment of version %s", versionID), err)
+	}
+	seen := map[string]int{}
+	for _, spec := range deployment.Deployment.DeploymentSpecifications {
+		key := joinNonEmpty(spec.GPU, spec.InstanceType)
+		seen[key]++
+		if seen[key] > 1 {
+			key = fmt.Sprintf("%s #%d", key, seen[key])
+		}
+		prefix := "deployment[" + key + "]."
+		add(prefix+"backend", spec.Backend)
+		add(prefix+"instances", fmt.Sprintf("%d-%d", spec.MinInstances, spec.MaxInstances))
+		add(prefix+"maxRequestConcurrency", formatNonZero(spec.MaxRequestConcurrency))
+		add(prefix+"regions", strings.Join(sortedCopy(spec.Regions), ", "))
+		add(prefix+"clusters", strings.Join(sortedCopy(spec.Clusters), ", "))
+		add(prefix+"availabilityZones", strings.Join(sortedCopy(spec.AvailabilityZones), ", "))
+		add(prefix+"attributes", strings.Join(sortedCopy(spec.Attributes), ", "))
+		add(prefix+"preferredOrder", formatNonZero(spec.PreferredOrder))
+		if spec.Configuration != nil {
+			configuration, err := json.Marshal(spec.Configuration)
+			if err == nil {
+				add(prefix+"configuration", string(configuration))
+			}
+		}
+	}
+	return fields, nil
+}
+
+// diffVersionFields lists the fields whose values differ, in the order of from
+// followed by the fields only to has
+func diffVersionFields(from, to []versionField) []fieldChange {
+	toByName := make(map[string]versionField, len(to))
+	for _, field := range to {
+		toByName[field.Name] = field
+	}
+	fromNames := make(map[string]bool, len(from))
+	changes := []fieldChange{}
+	for _, field := range from {
+		fromNames[field.Name] = true
+		other := toByName[field.Name]
+		if field.Value != other.Value {
+			changes = append(changes, changedField(field, other))
+		}
+	}
+	for _, field := range to {
+		if !fromNames[field.Name] && field.Value != "" {
+			changes = append(changes, changedField(versionField{Name: field.Name}, field))
+		}
+	}
+	return changes
+}
+
+func changedField(from, to versionField) fieldChange {
+	change := fieldChange{Field: from.Name, From: from.Display, To: to.Display}
+	if change.Field == "" {
+		change.Field = to.Name
+	}
+	// masked values look the same, so say that they changed
+	if change.From != "" && change.From == change.To {
+		change.To += " (changed)"
+	}
+	return change
+}
+
+func formatNonZero(n int64) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprint(n)
+}
+
+func sortedCopy(values []string) []string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,410 @@
+package function
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brevdev/nvcf/api"
+	"github.com/brevdev/nvcf/collections"
+	"github.com/brevdev/nvcf/output"
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+const (
+	defaultWaitTimeout = 30 * time.Minute // GFN can take a while
+	// polling starts at --poll-interval and slows down to maxPollInterval while the status does not change
+	defaultPollInterval = 5 * time.Second
+	maxPollInterval     = 30 * time.Second
+)
+
+// errDeploymentFailed is returned when a deployment ends in ERROR
+var errDeploymentFailed = errors.New("deployment failed")
+
+// waitOptions control how long and how often a deployment is polled
+type waitOptions struct {
+	Timeout      time.Duration
+	PollInterval time.Duration
+}
+
+// events of waiting for a deployment
+const (
+	deploymentEventStatus  = "status"
+	deploymentEventActive  = "active"
+	deploymentEventFailed  = "failed"
+	deploymentEventTimeout = "timeout"
+	deploymentEventError   = "error"
+
+	deploymentEventInterrupted = "interrupted"
+)
+
+// deploymentEvent is a step of waiting for a deployment. With --json each
+// event is printed as a line of JSON.
+type deploymentEvent struct {
+	Time       time.Time          `json:"time"`
+	Event      string             `json:"event"`
+	FunctionID string             `json:"functionId"`
+	VersionID  string             `json:"versionId"`
+	Status     string             `json:"status,omitempty"`
+	Elapsed    string             `json:"elapsed"`
+	Error      string             `json:"error,omitempty"`
+	Failure    *deploymentFailure `json:"failure,omitempty"`
+}
+
+// deploymentFailure explains why a deployment ended in ERROR
+type deploymentFailure struct {
+	HealthChecks []healthCheckFailure `json:"healthChecks,omitempty"`
+	Instances    []instanceStatus     `json:"instances,omitempty"`
+}
+
+type healthCheckFailure struct {
+	Backend      string `json:"backend"`
+	GPU          string `json:"gpu"`
+	InstanceType string `json:"instanceType,omitempty"`
+	Error        string `json:"error"`
+	SisRequestID string `json:"sisRequestId,omitempty"`
+}
+
+type instanceStatus struct {
+	InstanceID   string    `json:"instanceId"`
+	Status       string    `json:"status"`
+	Backend      string    `json:"backend,omitempty"`
+	GPU          string    `json:"gpu,omitempty"`
+	InstanceType string    `json:"instanceType,omitempty"`
+	Location     string    `json:"location,omitempty"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// addWaitFlags adds --wait-timeout and --poll-interval to a command that waits for a deployment
+func addWaitFlags(cmd *cobra.Command) {
+	cmd.Flags().Duration("wait-timeout", defaultWaitTimeout, "How long to wait for the deployment to become ACTIVE")
+	cmd.Flags().Duration("poll-interval", defaultPollInterval, fmt.Sprintf("How often to check the deployment. Checks slow down to every %s while the status does not change", maxPollInterval))
+}
+
+func waitOptionsFromFlags(cmd *cobra.Command) waitOptions {
+	opts := waitOptions{Timeout: defaultWaitTimeout, PollInterval: defaultPollInterval}
+	if timeout, err := cmd.Flags().GetDuration("wait-timeout"); err == nil && timeout > 0 {
+		opts.Timeout = timeout
+	}
+	if interval, err := cmd.Flags().GetDuration("poll-interval"); err == nil && interval > 0 {
+		opts.PollInterval = interval
+	}
+	return opts
+}
+
+// waitForDeploymentStatus polls the deployment until it is ACTIVE, fails or ctx is done.
+// Polls start pollInterval apart and back off while the status does not change.
+// onStatus, if set, is called with every status seen.
+func waitForDeploymentStatus(ctx context.Context, client *api.Client, functionID, versionID string, pollInterval time.Duration, onStatus func(status string)) error {
+	interval := pollInterval
+	var last nvcf.DeploymentResponseDeploymentFunctionStatus
+	for {
+		deploymentStatus, err := client.FunctionDeployment.Functions.Versions.GetDeployment(
+			ctx,
+			functionID,
+			versionID,
+		)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		status := deploymentStatus.Deployment.FunctionStatus
+		if onStatus != nil {
+			onStatus(string(status))
+		}
+		switch status {
+		case nvcf.DeploymentResponseDeploymentFunctionStatusActive:
+			return nil
+		case nvcf.DeploymentResponseDeploymentFunctionStatusError:
+			return errDeploymentFailed
+		}
+		if status == last {
+			interval = min(interval*3/2, max(pollInterval, maxPollInterval))
+		} else {
+			interval, last = pollInterval, status
+		}
+		if err := sleepContext(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// sleepContext sleeps for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// WaitForDeployment waits for a deployment to become ACTIVE as set by
+// --wait-timeout and --poll-interval, printing its status changes. An
+// interrupt is handled as set by --on-interrupt.
+func WaitForDeployment(cmd *cobra.Command, client *api.Client, functionID, versionID string) error {
+	onInterrupt, _ := cmd.Flags().GetString("on-interrupt")
+	if !collections.ListContains(interruptActions, onInterrupt) {
+		return output.Error(cmd, fmt.Sprintf("Invalid --on-interrupt %q, expected one of %s", onInterrupt, strings.Join(interruptActions, ", ")), nil)
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err := watchDeployment(ctx, cmd, client, functionID, versionID, waitOptionsFromFlags(cmd))
+	if ctx.Err() != nil && cmd.Context().Err() == nil {
+		// a second interrupt exits right away
+		stop()
+		return handleInterrupt(cmd, client, functionID, versionID, onInterrupt)
+	}
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Error waiting for deployment: %v", err), nil)
+	}
+	if asJSON, _ := cmd.Flags().GetBool("json"); !asJSON {
+		output.Success(cmd, fmt.Sprintf("\nFunction deployed (ID: %s, Version: %s)", functionID, versionID))
+	}
+	return nil
+}
+
+// watchDeployment waits for a deployment to become ACTIVE and prints a timeline of
+// its status changes. When the deployment ends in ERROR, its health-check failures
+// and instance statuses are shown.
+func watchDeployment(ctx context.Context, cmd *cobra.Command, client *api.Client, functionID, versionID string, opts waitOptions) error {
+	timeline := newDeploymentTimeline(cmd, functionID, versionID)
+	timeline.start()
+	defer timeline.stop()
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	err := waitForDeploymentStatus(ctx, client, functionID, versionID, opts.PollInterval, timeline.status)
+	switch {
+	case err == nil:
+		timeline.emit(deploymentEvent{Event: deploymentEventActive, Status: string(nvcf.DeploymentResponseDeploymentFunctionStatusActive)})
+	case errors.Is(err, errDeploymentFailed):
+		failure := deploymentFailureDetail(cmd.Context(), client, functionID, versionID)
+		timeline.emit(deploymentEvent{Event: deploymentEventFailed, Status: string(nvcf.DeploymentResponseDeploymentFunctionStatusError), Failure: failure})
+	case errors.Is(err, context.Canceled):
+		timeline.emit(deploymentEvent{Event: deploymentEventInterrupted, Error: "interrupted"})
+	case errors.Is(err, context.DeadlineExceeded):
+		err = fmt.Errorf("the deployment did not become ACTIVE within %s", opts.Timeout)
+		timeline.emit(deploymentEvent{Event: deploymentEventTimeout, Error: err.Error()})
+	default:
+		timeline.emit(deploymentEvent{Event: deploymentEventError, Error: err.Error()})
+	}
+	return err
+}
+
+// deploymentFailureDetail collects the health-check failures and instance statuses
+// of a failed deployment. Whatever cannot be read is left out.
+func deploymentFailureDetail(ctx context.Context, client *api.Client, functionID, versionID string) *deploymentFailure {
+	failure := &deploymentFailure{}
+	if deployment, err := client.FunctionDeployment.Functions.Versions.GetDeployment(ctx, functionID, versionID); err == nil {
+		for _, health := range deployment.Deployment.HealthInfo {
+			failure.HealthChecks = append(failure.HealthChecks, healthCheckFailure{
+				Backend:      health.Backend,
+				GPU:          health.GPU,
+				InstanceType: health.InstanceType,
+				Error:        health.Error,
+				SisRequestID: health.SisRequestID,
+			})
+		}
+	}
+	if function, err := client.Functions.Versions.Get(ctx, functionID, versionID, nvcf.FunctionVersionGetParams{}); err == nil {
+		for _, instance := range function.Function.ActiveInstances {
+			failure.Instances = append(failure.Instances, instanceStatus{
+				InstanceID:   instance.InstanceID,
+				Status:       string(instance.InstanceStatus),
+				Backend:      instance.Backend,
+				GPU:          instance.GPU,
+				InstanceType: instance.InstanceType,
+				Location:     instance.Location,
+				UpdatedAt:    instance.InstanceUpdatedAt,
+			})
+		}
+	}
+	return failure
+}
+
+// deploymentTimeline prints the status changes of a deployment with their time,
+// as lines below a spinner or, with --json, as JSON lines
+type deploymentTimeline struct {
+	cmd        *cobra.Command
+	functionID string
+	versionID  string
+	started    time.Time
+	last       string
+	asJSON     bool
+	quiet      bool
+	spinner    *spinner.Spinner
+}
+
+func newDeploymentTimeline(cmd *cobra.Command, functionID, versionID string) *deploymentTimeline {
+	asJSON, _ := cmd.Flags().GetBool("json")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	return &deploymentTimeline{
+		cmd:        cmd,
+		functionID: functionID,
+		versionID:  versionID,
+		started:    time.Now(),
+		asJSON:     asJSON,
+		quiet:      quiet,
+		spinner:    output.NewSpinner(fmt.Sprintf("Waiting for version %s to become active...", versionID)),
+	}
+}
+
+func (t *deploymentTimeline) start() {
+	if !t.asJSON && !t.quiet {
+		output.StartSpinner(t.spinner)
+	}
+}
+
+func (t *deploymentTimeline) stop() {
+	if t.spinner.Active() {
+		output.StopSpinner(t.spinner)
+	}
+}
+
+// status records a polled status, emitting an event when it changed
+func (t *deploymentTimeline) status(status string) {
+	if status == t.last {
+		return
+	}
+	t.last = status
+	t.emit(deploymentEvent{Event: deploymentEventStatus, Status: status})
+}
+
+func (t *deploymentTimeline) emit(event deploymentEvent) {
+	event.Time = time.Now().UTC()
+	event.FunctionID, event.VersionID = t.functionID, t.versionID
+	event.Elapsed = time.Since(t.started).Round(time.Second).String()
+	out := t.cmd.OutOrStdout()
+	if t.asJSON {
+		if data, err := json.Marshal(event); err == nil {
+			fmt.Fprintln(out, string(data))
+		}
+		return
+	}
+	// the final status was already printed when it was polled
+	if t.quiet || event.Event == deploymentEventActive {
+		return
+	}
+
+	active := t.spinner.Active()
+	if active {
+		output.StopSpinner(t.spinner)
+	}
+	switch event.Event {
+	case deploymentEventStatus:
+		fmt.Fprintf(out, "  %s  +%-8s %s\n", event.Time.Local().Format(time.TimeOnly), event.Elapsed, event.Status)
+	case deploymentEventFailed:
+		printDeploymentFailure(t.cmd, event.Failure)
+	default:
+		fmt.Fprintf(out, "  %s  +%-8s %s\n", event.Time.Local().Format(time.TimeOnly), event.Elapsed, event.Error)
+	}
+	if active {
+		output.StartSpinner(t.spinner)
+	}
+}
+
+func printDeploymentFailure(cmd *cobra.Command, failure *deploymentFailure) {
+	out := cmd.OutOrStdout()
+	if len(failure.HealthChecks) == 0 && len(failure.Instances) == 0 {
+		fmt.Fprintln(out, "\nNVCF reported no health-check failures or instances for this deployment.")
+		return
+	}
+	if len(failure.HealthChecks) > 0 {
+		fmt.Fprintln(out, "\nHealth-check failures:")
+		for _, health := range failure.HealthChecks {
+			fmt.Fprintf(out, "  %s: %s\n", joinNonEmpty(health.GPU, health.InstanceType, "on", health.Backend), health.Error)
+			if health.SisRequestID != "" {
+				fmt.Fprintf(out, "    SIS request: %s\n", health.SisRequestID)
+			}
+		}
+	}
+	if len(failure.Instances) > 0 {
+		fmt.Fprintln(out, "\nInstances:")
+		for _, instance := range failure.Instances {
+			line := fmt.Sprintf("  %s  %-9s  %s", instance.InstanceID, instance.Status,
+				joinNonEmpty(instance.GPU, instance.InstanceType, "on", instance.Backend, instance.Location))
+			if !instance.UpdatedAt.IsZero() {
+				line += ", updated " + instance.UpdatedAt.Local().Format(time.RFC3339)
+			}
+			fmt.Fprintln(out, line)
+		}
+	}
+}
+
+func joinNonEmpty(parts ...string) string {
+	return strings.Join(collections.Filter(parts, func(part string) bool { return part != "" }), " ")
+}
+
+// what to do with a deployment when its wait is interrupted
+const (
+	interruptAsk    = "ask"
+	interruptDetach = "detach"
+	interruptCancel = "cancel"
+	interruptForce  = "force"
+)
+
+var interruptActions = []string{interruptAsk, interruptDetach, interruptCancel, interruptForce}
+
+// addInterruptFlag adds --on-interrupt to a command that waits for a deployment
+func addInterruptFlag(cmd *cobra.Command) {
+	cmd.Flags().String("on-interrupt", interruptAsk, "What Ctrl-C does while waiting for the deployment: ask, detach (leave it running), cancel (stop it gracefully) or force (stop it now). ask detaches when input is not possible")
+}
+
+// handleInterrupt detaches from, cancels or force-stops a deployment whose wait was
+// interrupted, asking which unless the action is set
+func handleInterrupt(cmd *cobra.Command, client *api.Client, functionID, versionID, action string) error {
+	if action == interruptAsk {
+		action = interruptDetach
+		if output.CanPrompt(cmd) {
+			action = askInterruptAction()
+		}
+	}
+
+	if action == interruptDetach {
+		output.Info(cmd, fmt.Sprintf("Detached. The deployment of version %s keeps running; follow it with 'nvcf function watch %s'", versionID, functionID))
+		return nil
+	}
+	_, err := client.FunctionDeployment.Functions.Versions.DeleteDeployment(cmd.Context(), functionID, versionID, nvcf.FunctionDeploymentFunctionVersionDeleteDeploymentParams{
+		Graceful: nvcf.Bool(action == interruptCancel),
+	})
+	if err != nil {
+		return output.Error(cmd, fmt.Sprintf("Error stopping the deployment of version %s (%v). Stop it with 'nvcf function stop %s --version-id %s'", versionID, err, functionID, versionID), nil)
+	}
+	if action == interruptCancel {
+		return output.Error(cmd, fmt.Sprintf("Deployment cancelled: version %s is stopping gracefully", versionID), nil)
+	}
+	return output.Error(cmd, fmt.Sprintf("Deployment cancelled: version %s was stopped", versionID), nil)
+}
+
+// askInterruptAction asks what to do with an interrupted deployment. It detaches
+// when no answer can be read.
+func askInterruptAction() string {
+	for {
+		answer, err := output.ReadLine("The deployment is still running. [d]etach, [c]ancel it gracefully or [f]orce-stop it? ")
+		if err != nil {
+			return interruptDetach
+		}
+		switch strings.ToLower(answer) {
+		case "d", "detach":
+			return interruptDetach
+		case "c", "cancel":
+			return interruptCancel
+		case "f", "force", "force-stop":
+			return interruptForce
+		}
+	}
+}
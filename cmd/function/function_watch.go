@@ -6,7 +6,6 @@ import (
 	"time"
 
 	"github.com/brevdev/nvcf/api"
-	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -28,7 +27,7 @@ func functionWatchCmd() *cobra.Command {
 }
 
 func runFunctionWatch(cmd *cobra.Command, args []string) error {
-	client := api.NewClient(config.GetAPIKey())
+	client := newClient(cmd)
 	app := tview.NewApplication()
 	statusParams, err := parseStatusFlags(cmd)
 	if err != nil {
@@ -74,8 +74,8 @@ func buildNVCFOrgInformationURL(orgID string) string {
 	return fmt.Sprintf("/v3/orgs/%s/nvcf", orgID)
 }
 
-func getNVCFOrgInformation(orgID string) (OrgClusterGroupsResponse, error) {
-	client := api.NewClient(config.GetAPIKey())
+func getNVCFOrgInformation(apiKey, orgID string) (OrgClusterGroupsResponse, error) {
+	client := api.NewClient(apiKey)
 	url := buildNVCFOrgInformationURL(orgID)
 	var res OrgClusterGroupsResponse
 	err := client.Get(context.Background(), url, nil, &res)
@@ -85,8 +85,8 @@ func getNVCFOrgInformation(orgID string) (OrgClusterGroupsResponse, error) {
 	return res, nil
 }
 
-func getNVCFClusterGroups(ctx context.Context) (*nvcf.ClusterGroupsResponse, error) {
-	client := api.NewClient(config.GetAPIKey())
+func getNVCFClusterGroups(ctx context.Context, apiKey string) (*nvcf.ClusterGroupsResponse, error) {
+	client := api.NewClient(apiKey)
 	availableCluster, err := client.ClusterGroups.List(ctx)
 	if err != nil {
 		return nil, err
@@ -95,21 +95,25 @@ func getNVCFClusterGroups(ctx context.Context) (*nvcf.ClusterGroupsResponse, err
 }
 
 func GetAvailableInstanceTypes(ctx context.Context, backend, gpuType string) ([]nvcf.ClusterGroupsResponseClusterGroup, error) {
-	orgInfo, err := getNVCFOrgInformation(config.GetOrgID())
+	orgInfo, err := getNVCFOrgInformation(config.GetAPIKey(), config.GetOrgID())
 	if err != nil {
 		return nil, err
 	}
-	clusterGroups, err := getNVCFClusterGroups(ctx)
+	clusterGroups, err := getNVCFClusterGroups(ctx, config.GetAPIKey())
 	if err != nil {
 		return nil, err
 	}
+	return availableClusterGroups(clusterGroups.ClusterGroups, orgInfo), nil
+}
 
+// availableClusterGroups keeps the cluster groups the org is allowed to deploy to
+func availableClusterGroups(clusterGroups []nvcf.ClusterGroupsResponseClusterGroup, orgInfo OrgClusterGroupsResponse) []nvcf.ClusterGroupsResponseClusterGroup {
 	gpuConfigs := make(map[string]bool)
 	for _, cluster := range orgInfo.Clusters {
 		gpuConfigs[cluster.Cluster] = true
 	}
 
-	filteredGroups := collections.Filter(clusterGroups.ClusterGroups, func(cluster nvcf.ClusterGroupsResponseClusterGroup) bool {
+	return collections.Filter(clusterGroups, func(cluster nvcf.ClusterGroupsResponseClusterGroup) bool {
 		// Check if the cluster is owned by the organization (BYOC)
 		if cluster.NcaID == orgInfo.BillingAccountID {
 			return true
@@ -129,6 +133,4 @@ func GetAvailableInstanceTypes(ctx context.Context, backend, gpuType string) ([]
 
 		return false
 	})
-
-	return filteredGroups, nil
 }
@@ -0,0 +1,164 @@
+package gpu
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/brevdev/nvcf/config"
+	"github.com/brevdev/nvcf/flagutil"
+	"github.com/tmc/nvcf-go"
+)
+
+// maxSuggestions is the number of valid combinations suggested for an invalid deployment
+const maxSuggestions = 5
+
+// Combination is a backend, GPU and instance type that the org can deploy to.
+// Available is the number of instances the org can still start on it, or -1 if unknown.
+type Combination struct {
+	Backend      string
+	GPU          string
+	InstanceType string
+	Available    int
+}
+
+func (c Combination) String() string {
+	s := fmt.Sprintf("--backend %s --gpu %s --instance-type %s", c.Backend, c.GPU, c.InstanceType)
+	if c.Available >= 0 {
+		s += fmt.Sprintf(" (%d instance(s) available)", c.Available)
+	}
+	return s
+}
+
+// InvalidDeploymentError reports a deployment that would be rejected, with the
+// valid combinations nearest to the one requested.
+type InvalidDeploymentError struct {
+	Reason      string
+	Suggestions []Combination
+}
+
+func (e *InvalidDeploymentError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return e.Reason
+	}
+	lines := []string{e.Reason, "Nearest valid combinations:"}
+	for _, suggestion := range e.Suggestions {
+		lines = append(lines, "  "+suggestion.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateDeployment checks that the org can deploy maxInstances instances of
+// gpuType on instanceType in backend. It returns an *InvalidDeploymentError when
+// the combination is not available to the org or lacks the capacity.
+func ValidateDeployment(ctx context.Context, backend, gpuType, instanceType string, maxInstances int64) error {
+	combinations, err := AvailableCombinations(ctx)
+	if err != nil {
+		return err
+	}
+	return ValidateCombination(combinations, backend, gpuType, instanceType, maxInstances)
+}
+
+// AvailableCombinations lists every backend, GPU and instance type available to
+// the org, with the capacity the org has left where it is known.
+func AvailableCombinations(ctx context.Context) ([]Combination, error) {
+	return AvailableCombinationsForOrg(ctx, config.GetAPIKey(), config.GetOrgID())
+}
+
+// AvailableCombinationsForOrg is AvailableCombinations for the org of other
+// credentials, such as those of a profile
+func AvailableCombinationsForOrg(ctx context.Context, apiKey, orgID string) ([]Combination, error) {
+	orgInfo, err := getNVCFOrgInformation(apiKey, orgID)
+	if err != nil {
+		return nil, err
+	}
+	clusterGroups, err := getNVCFClusterGroups(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return availableCombinations(availableClusterGroups(clusterGroups.ClusterGroups, orgInfo), orgInfo), nil
+}
+
+// ValidateCombination is ValidateDeployment against combinations that have already been listed
+func ValidateCombination(combinations []Combination, backend, gpuType, instanceType string, maxInstances int64) error {
+	requested := Combination{Backend: backend, GPU: gpuType, InstanceType: instanceType}
+	for _, combination := range combinations {
+		if combination.Backend != backend || combination.GPU != gpuType || combination.InstanceType != instanceType {
+			continue
+		}
+		if combination.Available < 0 || int64(combination.Available) >= maxInstances {
+			return nil
+		}
+		enough := func(c Combination) bool { return c.Available < 0 || int64(c.Available) >= maxInstances }
+		return &InvalidDeploymentError{
+			Reason: fmt.Sprintf("max-instances %d exceeds the %d instance(s) the org has available for %s %s on %s",
+				maxInstances, combination.Available, gpuType, instanceType, backend),
+			Suggestions: nearest(combinations, requested, enough),
+		}
+	}
+	return &InvalidDeploymentError{
+		Reason:      fmt.Sprintf("GPU %q with instance type %q is not available on backend %q for this org", gpuType, instanceType, backend),
+		Suggestions: nearest(combinations, requested, func(Combination) bool { return true }),
+	}
+}
+
+// availableCombinations lists every backend, GPU and instance type in the cluster
+// groups, with the capacity left for the org where it is known
+func availableCombinations(clusterGroups []nvcf.ClusterGroupsResponseClusterGroup, orgInfo OrgClusterGroupsResponse) []Combination {
+	var combinations []Combination
+	for _, group := range clusterGroups {
+		for _, gpu := range group.GPUs {
+			for _, instanceType := range gpu.InstanceTypes {
+				combination := Combination{Backend: group.Name, GPU: gpu.Name, InstanceType: instanceType.Name, Available: -1}
+				for _, cluster := range orgInfo.Clusters {
+					if cluster.Cluster == group.Name && cluster.GpuType == gpu.Name && cluster.InstanceType == instanceType.Name {
+						combination.Available = max(cluster.MaxInstances-cluster.CurrentInstances, 0)
+					}
+				}
+				combinations = append(combinations, combination)
+			}
+		}
+	}
+	return combinations
+}
+
+// nearest returns the combinations accepted by ok that are closest to requested:
+// those sharing the most fields first, then those with the most similar names
+func nearest(combinations []Combination, requested Combination, ok func(Combination) bool) []Combination {
+	type candidate struct {
+		Combination
+		matches  int
+		distance int
+	}
+	var candidates []candidate
+	for _, combination := range combinations {
+		if !ok(combination) || (combination.Backend == requested.Backend && combination.GPU == requested.GPU && combination.InstanceType == requested.InstanceType) {
+			continue
+		}
+		c := candidate{Combination: combination}
+		for _, pair := range [][2]string{
+			{combination.Backend, requested.Backend},
+			{combination.GPU, requested.GPU},
+			{combination.InstanceType, requested.InstanceType},
+		} {
+			if strings.EqualFold(pair[0], pair[1]) {
+				c.matches++
+			}
+			c.distance += flagutil.EditDistance(strings.ToLower(pair[0]), strings.ToLower(pair[1]))
+		}
+		candidates = append(candidates, c)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].matches != candidates[j].matches {
+			return candidates[i].matches > candidates[j].matches
+		}
+		return candidates[i].distance < candidates[j].distance
+	})
+
+	var suggestions []Combination
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].Combination)
+	}
+	return suggestions
+}
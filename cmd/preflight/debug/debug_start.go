@@ -1,13 +1,11 @@
 package debug
 
 import (
-	"bufio"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/brevdev/nvcf/api"
 	"github.com/brevdev/nvcf/cmd/preflight/brev"
+	"github.com/brevdev/nvcf/collections"
 	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
 	"github.com/google/uuid"
@@ -27,17 +25,24 @@ container image, and runs it in a debugging session. It allows you to:
 - Specify a particular version for debugging
 - Access the debug environment via SSH for hands-on troubleshooting
 
-Use this command when you need to investigate issues with your NVCF function in a live environment.`,
+Use this command when you need to investigate issues with your NVCF function in a live environment.
+With --dry-run, the instance that would be created is printed and nothing is created.`,
 		Args: cobra.ExactArgs(1),
 		RunE: runDebugStart,
 	}
 
 	cmd.Flags().String("version-id", "", "The ID of the version")
+	cmd.Flags().String("select", "", output.SelectFlagUsage)
 	return cmd
 }
 
 func runDebugStart(cmd *cobra.Command, args []string) error {
-	nvcfClient := api.NewClient(config.GetAPIKey())
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	var clientOptions []api.Option
+	if dryRun {
+		clientOptions = append(clientOptions, api.WithDryRun(cmd.OutOrStdout()))
+	}
+	nvcfClient := api.NewClient(config.GetAPIKey(), clientOptions...)
 	brevClient := brev.NewBrevClient()
 
 	functionId := args[0]
@@ -49,28 +54,17 @@ func runDebugStart(cmd *cobra.Command, args []string) error {
 			return output.Error(cmd, "Error listing function versions", err)
 		}
 
-		errorVersions := []string{}
-		for _, version := range versions.Functions {
-			if version.Status == nvcf.ListFunctionsResponseFunctionsStatusError {
-				errorVersions = append(errorVersions, version.VersionID)
-			}
-		}
+		errorVersions := collections.Filter(versions.Functions, func(version nvcf.ListFunctionsResponseFunction) bool {
+			return version.Status == nvcf.ListFunctionsResponseFunctionsStatusError
+		})
 
 		if len(errorVersions) == 0 {
 			return output.Error(cmd, "No versions with ERROR status found", nil)
 		}
 
-		if len(errorVersions) == 1 {
-			versionId = errorVersions[0]
-		} else {
-			output.Info(cmd, "Multiple versions with ERROR status found. Please select a version to debug:")
-			for _, version := range errorVersions {
-				output.Info(cmd, fmt.Sprintf("Version ID: %s", version))
-			}
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Print("Enter version-id to debug: ")
-			versionId, _ = reader.ReadString('\n')
-			versionId = strings.TrimSpace(versionId)
+		versionId, err = output.SelectVersion(cmd, errorVersions, "debug")
+		if err != nil {
+			return err
 		}
 	} else {
 		targetVersion, err := nvcfClient.FunctionDeployment.Functions.Versions.GetDeployment(cmd.Context(), functionId, versionId)
@@ -93,6 +87,16 @@ func runDebugStart(cmd *cobra.Command, args []string) error {
 	image := deployment.Function.ContainerImage
 	imageArgs := deployment.Function.ContainerArgs
 
+	// i want to append a random 5 character string to the end of the instance name
+	randomString := uuid.New().String()[:5]
+	instanceName := fmt.Sprintf("nvcf-%s-debug-%s", functionId, randomString)
+
+	// the Brev VM is billed, so a dry run only describes it
+	if dryRun {
+		fmt.Fprintf(cmd.OutOrStdout(), "Dry run: would create the Brev GPU instance %s and run %s on it. No instance is created.\n", instanceName, image)
+		return nil
+	}
+
 	if !brevClient.IsBrevCLIInstalled() {
 		return fmt.Errorf("brev CLI is not installed. Please install it first")
 	}
@@ -112,10 +116,6 @@ func runDebugStart(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println("Setting up a GPU powered VM for debugging")
 
-	// i want to append a random 5 character string to the end of the instance name
-	randomString := uuid.New().String()[:5]
-	instanceName := fmt.Sprintf("nvcf-%s-debug-%s", functionId, randomString)
-
 	// hit the brev api to create an instance using
 	if err := brevClient.CreateInstance(functionId, instanceName); err != nil {
 		return output.Error(cmd, "Error creating Brev instance", err)
@@ -0,0 +1,128 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brevdev/nvcf/functionspec"
+	"github.com/brevdev/nvcf/output"
+	"github.com/spf13/cobra"
+)
+
+// SpecCmd returns a cobra.Command for working with YAML function specifications
+// offline, without calling the NVCF API.
+func SpecCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Work with YAML function specifications",
+		Long: `Validate and render YAML function specifications and print their JSON Schema. These commands do not call the NVCF API.
+
+Specs can reference variables as ${VAR} or ${VAR:-default}. Values passed with --var take precedence over environment variables.
+Overlays passed with --overlay are deep-merged over the base spec in order: mappings are merged key by key, a null value removes a key,
+functions and models are matched by name, environment variables by key, and any other value replaces the base value.`,
+	}
+
+	cmd.AddCommand(specValidateCmd())
+	cmd.AddCommand(specRenderCmd())
+	cmd.AddCommand(specSchemaCmd())
+
+	return cmd
+}
+
+func specValidateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "validate <file>",
+		Short: "Validate a function specification",
+		Long: `Validate a YAML function specification against the published schema without calling the API.
+Unknown fields, missing required fields, out of range ports, invalid health protocols and functions without a container image are reported with their file, line and column.
+Use --deploy to also require the inst_* fields needed by 'nvcf function create --file --deploy'.`,
+		Example:      "nvcf spec validate deploy.yaml --deploy",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         runSpecValidate,
+	}
+	cmd.Flags().Bool("deploy", false, "Also require the fields needed to deploy each function")
+	addResolveFlags(cmd)
+	return cmd
+}
+
+func runSpecValidate(cmd *cobra.Command, args []string) error {
+	opts, err := resolveOptions(cmd)
+	if err != nil {
+		return output.Error(cmd, err.Error(), nil)
+	}
+	opts.Deploy, _ = cmd.Flags().GetBool("deploy")
+
+	spec, err := functionspec.Load(args[0], opts)
+	if err != nil {
+		var validationErrs functionspec.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return fmt.Errorf("%s\n%d problem(s) found", validationErrs, len(validationErrs))
+		}
+		return output.Error(cmd, fmt.Sprintf("Error reading %s", args[0]), err)
+	}
+
+	output.Success(cmd, fmt.Sprintf("%s is valid (%d function(s))", args[0], len(spec.Functions)))
+	return nil
+}
+
+func specRenderCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "render <file>",
+		Short: "Print a function specification with overlays and variables resolved",
+		Long: `Print the final function specification after merging overlays and expanding variables, exactly as 'nvcf function create --file' would use it.
+The rendered spec is validated first.`,
+		Example:      "nvcf spec render base.yaml --overlay prod.yaml --var IMAGE_TAG=1.2.0",
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE:         runSpecRender,
+	}
+	addResolveFlags(cmd)
+	return cmd
+}
+
+func runSpecRender(cmd *cobra.Command, args []string) error {
+	opts, err := resolveOptions(cmd)
+	if err != nil {
+		return output.Error(cmd, err.Error(), nil)
+	}
+
+	data, err := functionspec.Render(args[0], opts)
+	if err != nil {
+		var validationErrs functionspec.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return fmt.Errorf("%s\n%d problem(s) found", validationErrs, len(validationErrs))
+		}
+		return output.Error(cmd, fmt.Sprintf("Error rendering %s", args[0]), err)
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
+func addResolveFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSlice("overlay", nil, "Spec file to deep-merge over the base spec (can be used multiple times)")
+	cmd.Flags().StringArray("var", nil, "Variable for ${VAR} references, format: KEY=VALUE (can be used multiple times)")
+}
+
+func resolveOptions(cmd *cobra.Command) (functionspec.Options, error) {
+	overlays, _ := cmd.Flags().GetStringSlice("overlay")
+	varFlags, _ := cmd.Flags().GetStringArray("var")
+	vars, err := functionspec.ParseVars(varFlags)
+	if err != nil {
+		return functionspec.Options{}, err
+	}
+	return functionspec.Options{Vars: vars, Overlays: overlays}, nil
+}
+
+func specSchemaCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "schema",
+		Short: "Print the JSON Schema for function specifications",
+		Long:  `Print the JSON Schema for function specifications. Point your editor's YAML language server at it to get completion and validation while editing specs.`,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := cmd.OutOrStdout().Write(functionspec.Schema())
+			return err
+		},
+	}
+}
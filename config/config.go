@@ -5,11 +5,24 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 )
 
 type Config struct {
 	APIKey string `json:"api_key"`
 	OrgID  string `json:"org_id"`
+	// ProtectedFunctions are function IDs that delete and stop refuse to touch
+	// without --override-protection
+	ProtectedFunctions []string `json:"protected_functions,omitempty"`
+	// Profiles are named credentials for other orgs, used by commands that
+	// work across orgs such as 'nvcf function clone --to-profile'
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+}
+
+// Profile is an API key and the org it is used with
+type Profile struct {
+	APIKey string `json:"api_key"`
+	OrgID  string `json:"org_id"`
 }
 
 var cfg Config
@@ -47,6 +60,45 @@ func GetOrgID() string {
 	return cfg.OrgID
 }
 
+// IsProtectedFunction reports whether functionID is listed in protected_functions
+func IsProtectedFunction(functionID string) bool {
+	for _, id := range cfg.ProtectedFunctions {
+		if id == functionID {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProfile returns the named profile
+func GetProfile(name string) (Profile, bool) {
+	profile, ok := cfg.Profiles[name]
+	return profile, ok
+}
+
+// ProfileNames lists the names of the profiles, sorted
+func ProfileNames() []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func SetProfile(name string, profile Profile) error {
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]Profile{}
+	}
+	cfg.Profiles[name] = profile
+	return saveConfig()
+}
+
+func DeleteProfile(name string) error {
+	delete(cfg.Profiles, name)
+	return saveConfig()
+}
+
 func SetAPIKey(apiKey string) error {
 	cfg.APIKey = apiKey
 	return saveConfig()
@@ -46,3 +46,54 @@ func DurationToISO8601(d time.Duration) string {
 	}
 	return result.String()
 }
+
+// ISO8601ToDuration parses an ISO 8601 duration string such as the ones produced
+// by DurationToISO8601 or returned by the NVCF API (e.g. PT20S, P2DT4H, PT1.5S).
+// It is the inverse of DurationToISO8601 and uses the same 365 day year and
+// 30 day month approximations.
+func ISO8601ToDuration(s string) (time.Duration, error) {
+	if len(s) < 2 || s[0] != 'P' {
+		return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+	}
+	day := 24 * time.Hour
+	dateUnits := map[byte]time.Duration{'Y': 365 * day, 'M': 30 * day, 'W': 7 * day, 'D': day}
+	timeUnits := map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == 'T':
+			if inTime || number != "" {
+				return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+			}
+			inTime = true
+		case (c >= '0' && c <= '9') || c == '.' || c == ',':
+			if c == ',' {
+				c = '.'
+			}
+			number += string(c)
+		default:
+			units := dateUnits
+			if inTime {
+				units = timeUnits
+			}
+			unit, ok := units[c]
+			if !ok || number == "" {
+				return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+			}
+			var value float64
+			if _, err := fmt.Sscanf(number, "%g", &value); err != nil {
+				return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+			}
+			total += time.Duration(value * float64(unit))
+			number = ""
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration: %q", s)
+	}
+	return total, nil
+}
@@ -0,0 +1,218 @@
+package functionspec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ParseEnv parses container environment variables given on the command line.
+// Each value is KEY=VALUE, the legacy key:value, or a bare KEY whose value is
+// taken from the local environment.
+func ParseEnv(values []string) ([]EnvVar, error) {
+	var env []EnvVar
+	for _, value := range values {
+		envVar, err := parseEnvValue(value)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, envVar)
+	}
+	return env, nil
+}
+
+func parseEnvValue(s string) (EnvVar, error) {
+	if key, value, ok := strings.Cut(s, "="); ok && envKeyPattern.MatchString(key) {
+		return EnvVar{Key: key, Value: value}, nil
+	}
+	if key, value, ok := strings.Cut(s, ":"); ok && key != "" {
+		return EnvVar{Key: key, Value: value}, nil
+	}
+	if envKeyPattern.MatchString(s) {
+		value, ok := os.LookupEnv(s)
+		if !ok {
+			return EnvVar{}, fmt.Errorf("environment variable %s is not set locally; use %s=<value> to set it explicitly", s, s)
+		}
+		return EnvVar{Key: s, Value: value}, nil
+	}
+	return EnvVar{}, fmt.Errorf("invalid environment variable %q, expected KEY=VALUE, key:value or the name of a local environment variable", s)
+}
+
+// ReadEnvFile reads a dotenv file: KEY=VALUE lines with optional export
+// prefixes, # comments, and single or double quoted values. Double quoted
+// values may span lines and support \n, \t, \" and \\ escapes.
+func ReadEnvFile(path string) ([]EnvVar, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseEnvFile(path, string(data))
+}
+
+func parseEnvFile(filename, data string) ([]EnvVar, error) {
+	var env []EnvVar
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", filename, lineNumber)
+		}
+		value = strings.TrimSpace(value)
+
+		switch {
+		case strings.HasPrefix(value, `"`):
+			// a double quoted value ends at the first unescaped quote, possibly on a later line
+			quoted := value[1:]
+			for {
+				if end := closingQuote(quoted); end >= 0 {
+					if rest := strings.TrimSpace(quoted[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+						return nil, fmt.Errorf("%s:%d: unexpected text after closing quote", filename, lineNumber)
+					}
+					value = unescapeEnvValue(quoted[:end])
+					break
+				}
+				i++
+				if i >= len(lines) {
+					return nil, fmt.Errorf("%s:%d: unterminated double quoted value", filename, lineNumber)
+				}
+				quoted += "\n" + lines[i]
+			}
+		case strings.HasPrefix(value, "'"):
+			end := strings.Index(value[1:], "'")
+			if end < 0 {
+				return nil, fmt.Errorf("%s:%d: unterminated single quoted value", filename, lineNumber)
+			}
+			value = value[1 : end+1]
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+		}
+		env = append(env, EnvVar{Key: key, Value: value})
+	}
+	return env, nil
+}
+
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func unescapeEnvValue(s string) string {
+	replacer := strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+	return replacer.Replace(s)
+}
+
+// MergeEnv combines environment variable lists. A key set in a later list
+// overrides the earlier value but keeps its original position.
+func MergeEnv(lists ...[]EnvVar) []EnvVar {
+	var merged []EnvVar
+	index := map[string]int{}
+	for _, list := range lists {
+		for _, envVar := range list {
+			if i, ok := index[envVar.Key]; ok {
+				merged[i] = envVar
+				continue
+			}
+			index[envVar.Key] = len(merged)
+			merged = append(merged, envVar)
+		}
+	}
+	return merged
+}
+
+// normalizeEnv rewrites the environment of every function into key/value
+// mappings: entries of envFile are loaded, string entries are parsed like
+// --env values and entries without a value take it from the local environment.
+// envFile paths are relative to the spec file that sets them.
+func (d *document) normalizeEnv(errs *ValidationErrors) {
+	functions := mappingValue(d.root, "functions")
+	if functions == nil {
+		return
+	}
+	for i, fn := range functions.Content {
+		path := fmt.Sprintf("functions[%d]", i)
+		var fromFile []EnvVar
+		if index := mappingIndex(fn, "envFile"); index >= 0 {
+			node := fn.Content[index+1]
+			envFile := node.Value
+			if !filepath.IsAbs(envFile) {
+				envFile = filepath.Join(filepath.Dir(d.origins[node]), envFile)
+			}
+			env, err := ReadEnvFile(envFile)
+			if err != nil {
+				errs.add(node, joinPath(path, "envFile"), err.Error())
+				continue
+			}
+			fromFile = env
+			fn.Content = append(fn.Content[:index], fn.Content[index+2:]...)
+		}
+
+		var inline []EnvVar
+		envNode := mappingValue(fn, "containerEnvironment")
+		if envNode != nil {
+			for j, item := range envNode.Content {
+				envVar, err := envVarFromNode(item)
+				if err != nil {
+					errs.add(item, fmt.Sprintf("%s.containerEnvironment[%d]", path, j), err.Error())
+					continue
+				}
+				inline = append(inline, envVar)
+			}
+		}
+
+		env := MergeEnv(fromFile, inline)
+		if len(env) == 0 {
+			continue
+		}
+		if envNode == nil {
+			envNode = &yaml.Node{Kind: yaml.SequenceNode}
+			fn.Content = append(fn.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "containerEnvironment"}, envNode)
+		}
+		envNode.Content = envNode.Content[:0]
+		for _, envVar := range env {
+			envNode.Content = append(envNode.Content, &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Value: "key"},
+				{Kind: yaml.ScalarNode, Value: envVar.Key},
+				{Kind: yaml.ScalarNode, Value: "value"},
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: envVar.Value},
+			}})
+		}
+	}
+}
+
+func envVarFromNode(node *yaml.Node) (EnvVar, error) {
+	if node.Kind == yaml.ScalarNode {
+		return parseEnvValue(node.Value)
+	}
+	key := mappingValue(node, "key").Value
+	value := mappingValue(node, "value")
+	if value != nil && value.ShortTag() != "!!null" {
+		return EnvVar{Key: key, Value: value.Value}, nil
+	}
+	local, ok := os.LookupEnv(key)
+	if !ok {
+		return EnvVar{}, fmt.Errorf("%s has no value and is not set in the local environment", key)
+	}
+	return EnvVar{Key: key, Value: local}, nil
+}
@@ -0,0 +1,85 @@
+package functionspec
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// mergeKeys identify the items of a list of mappings that an overlay merges
+// into instead of replacing: functions and models by name, environment variables by key.
+var mergeKeys = []string{"name", "key"}
+
+// mergeNodes deep-merges overlay over base and returns the result. Mappings are
+// merged key by key and a null value removes the key. Lists of mappings that all
+// carry a merge key are merged item by item, appending new items. Anything else
+// in the overlay replaces the base value.
+func mergeNodes(base, overlay *yaml.Node) *yaml.Node {
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(overlay.Content); i += 2 {
+			key, value := overlay.Content[i], overlay.Content[i+1]
+			index := mappingIndex(base, key.Value)
+			switch {
+			case value.ShortTag() == "!!null" && index >= 0:
+				base.Content = append(base.Content[:index], base.Content[index+2:]...)
+			case value.ShortTag() == "!!null":
+			case index >= 0:
+				base.Content[index+1] = mergeNodes(base.Content[index+1], value)
+			default:
+				base.Content = append(base.Content, key, value)
+			}
+		}
+		return base
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode:
+		mergeKey := sequenceMergeKey(base, overlay)
+		if mergeKey == "" {
+			return overlay
+		}
+		for _, item := range overlay.Content {
+			id := mappingValue(item, mergeKey).Value
+			if match := findItem(base, mergeKey, id); match != nil {
+				mergeNodes(match, item)
+			} else {
+				base.Content = append(base.Content, item)
+			}
+		}
+		return base
+	default:
+		return overlay
+	}
+}
+
+func sequenceMergeKey(sequences ...*yaml.Node) string {
+	for _, key := range mergeKeys {
+		matches := true
+		for _, sequence := range sequences {
+			for _, item := range sequence.Content {
+				if mappingValue(item, key) == nil {
+					matches = false
+				}
+			}
+		}
+		if matches {
+			return key
+		}
+	}
+	return ""
+}
+
+func findItem(sequence *yaml.Node, key, id string) *yaml.Node {
+	for _, item := range sequence.Content {
+		if mappingValue(item, key).Value == id {
+			return item
+		}
+	}
+	return nil
+}
+
+// mappingIndex returns the index of key in a mapping node's content, or -1
+func mappingIndex(node *yaml.Node, key string) int {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,171 @@
+package functionspec
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderOverlays(t *testing.T) {
+	const base = `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    containerArgs: --verbose
+    tags: [team-a, beta]
+    containerEnvironment:
+      - key: MODE
+        value: slow
+      - key: LOG_LEVEL
+        value: info
+    models:
+      - name: llama
+        version: "3"
+        uri: ngc://org/llama
+      - name: embed
+        version: "1"
+        uri: ngc://org/embed
+  - name: chat
+    inferenceUrl: /chat
+`
+	tests := []struct {
+		name     string
+		overlays []string
+		want     string
+	}{
+		{
+			name: "null removes a key",
+			overlays: []string{`functions:
+  - name: app
+    containerArgs: null
+    models: null
+`},
+			want: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    tags: [team-a, beta]
+    containerEnvironment:
+      - key: MODE
+        value: slow
+      - key: LOG_LEVEL
+        value: info
+  - name: chat
+    inferenceUrl: /chat
+`,
+		},
+		{
+			name: "functions and models are matched by name",
+			overlays: []string{`functions:
+  - name: app
+    models:
+      - name: llama
+        version: "4"
+  - name: batch
+    inferenceUrl: /batch
+`},
+			want: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    containerArgs: --verbose
+    tags: [team-a, beta]
+    containerEnvironment:
+      - key: MODE
+        value: slow
+      - key: LOG_LEVEL
+        value: info
+    models:
+      - name: llama
+        version: "4"
+        uri: ngc://org/llama
+      - name: embed
+        version: "1"
+        uri: ngc://org/embed
+  - name: chat
+    inferenceUrl: /chat
+  - name: batch
+    inferenceUrl: /batch
+`,
+		},
+		{
+			name: "environment variables are matched by key and lists of strings are replaced",
+			overlays: []string{`functions:
+  - name: app
+    tags: [team-b]
+    containerEnvironment:
+      - key: MODE
+        value: fast
+      - key: REGION
+        value: us
+`},
+			want: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    containerArgs: --verbose
+    tags: [team-b]
+    containerEnvironment:
+      - key: MODE
+        value: fast
+      - key: LOG_LEVEL
+        value: info
+      - key: REGION
+        value: us
+    models:
+      - name: llama
+        version: "3"
+        uri: ngc://org/llama
+      - name: embed
+        version: "1"
+        uri: ngc://org/embed
+  - name: chat
+    inferenceUrl: /chat
+`,
+		},
+		{
+			name: "overlays apply in order",
+			overlays: []string{
+				"fn_image: nvcr.io/org/app:2\nfunctions:\n  - name: chat\n    inferenceUrl: /v2/chat\n",
+				"fn_image: nvcr.io/org/app:3\nfunctions:\n  - name: app\n    containerEnvironment: null\n    models: null\n",
+			},
+			want: `fn_image: nvcr.io/org/app:3
+functions:
+  - name: app
+    inferenceUrl: /infer
+    containerArgs: --verbose
+    tags: [team-a, beta]
+  - name: chat
+    inferenceUrl: /v2/chat
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			basePath := writeFile(t, dir, "base.yaml", base)
+			var overlays []string
+			for i, overlay := range tt.overlays {
+				overlays = append(overlays, writeFile(t, dir, fmt.Sprintf("overlay%d.yaml", i), overlay))
+			}
+			got, err := Render(basePath, Options{Overlays: overlays})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,196 @@
+package functionspec
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/brevdev/nvcf/collections"
+	"github.com/brevdev/nvcf/flagutil"
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed schema.json
+var schemaJSON []byte
+
+// Schema returns the published JSON Schema for the function specification.
+func Schema() []byte {
+	return schemaJSON
+}
+
+// schema is the subset of JSON Schema used by schema.json. It is evaluated
+// directly against the YAML node tree so that errors keep their line and column.
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Properties           map[string]*schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *schema            `json:"items"`
+	Enum                 []string           `json:"enum"`
+	Minimum              *int64             `json:"minimum"`
+	Maximum              *int64             `json:"maximum"`
+	MinLength            *int               `json:"minLength"`
+	MinItems             *int               `json:"minItems"`
+	OneOf                []*schema          `json:"oneOf"`
+	Definitions          map[string]*schema `json:"definitions"`
+}
+
+var rootSchema = mustParseSchema(schemaJSON)
+
+func mustParseSchema(data []byte) *schema {
+	var s schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		panic(fmt.Sprintf("functionspec: invalid embedded schema: %v", err))
+	}
+	return &s
+}
+
+func (s *schema) resolve(root *schema) *schema {
+	if s.Ref == "" {
+		return s
+	}
+	name := strings.TrimPrefix(s.Ref, "#/definitions/")
+	def, ok := root.Definitions[name]
+	if !ok {
+		panic(fmt.Sprintf("functionspec: unknown schema reference %s", s.Ref))
+	}
+	return def
+}
+
+// validateNode checks node against s and appends an error for every violation
+func validateNode(errs *ValidationErrors, root, s *schema, node *yaml.Node, path string) {
+	s = s.resolve(root)
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		return
+	}
+	if len(s.OneOf) > 0 {
+		// the alternatives differ by type, so the node's kind picks the one to check
+		for _, alternative := range s.OneOf {
+			if kindMatches(alternative.resolve(root).Type, node) {
+				validateNode(errs, root, alternative, node, path)
+				return
+			}
+		}
+		errs.add(node, path, "expected a string or a mapping")
+		return
+	}
+
+	switch s.Type {
+	case "object":
+		if node.Kind != yaml.MappingNode {
+			errs.add(node, path, "expected a mapping")
+			return
+		}
+		seen := map[string]bool{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			seen[key.Value] = true
+			prop, ok := s.Properties[key.Value]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					errs.add(key, path, unknownFieldMessage(key.Value, s.Properties))
+				}
+				continue
+			}
+			validateNode(errs, root, prop, value, joinPath(path, key.Value))
+		}
+		for _, name := range s.Required {
+			if !seen[name] {
+				errs.add(node, path, fmt.Sprintf("missing required field %q", name))
+			}
+		}
+	case "array":
+		if node.Kind != yaml.SequenceNode {
+			errs.add(node, path, "expected a list")
+			return
+		}
+		if s.MinItems != nil && len(node.Content) < *s.MinItems {
+			errs.add(node, path, fmt.Sprintf("expected at least %d item(s)", *s.MinItems))
+		}
+		if s.Items != nil {
+			for i, item := range node.Content {
+				validateNode(errs, root, s.Items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case "string":
+		if node.Kind != yaml.ScalarNode {
+			errs.add(node, path, "expected a string")
+			return
+		}
+		if s.MinLength != nil && len(node.Value) < *s.MinLength {
+			errs.add(node, path, "must not be empty")
+		}
+		if len(s.Enum) > 0 && !collections.ListContains(s.Enum, node.Value) {
+			errs.add(node, path, fmt.Sprintf("invalid value %q, expected one of %s", node.Value, strings.Join(s.Enum, ", ")))
+		}
+	case "integer":
+		value, err := strconv.ParseInt(node.Value, 0, 64)
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" || err != nil {
+			errs.add(node, path, fmt.Sprintf("expected an integer, got %q", node.Value))
+			return
+		}
+		if s.Minimum != nil && value < *s.Minimum {
+			errs.add(node, path, fmt.Sprintf("must be at least %d", *s.Minimum))
+		}
+		if s.Maximum != nil && value > *s.Maximum {
+			errs.add(node, path, fmt.Sprintf("must be at most %d", *s.Maximum))
+		}
+	case "boolean":
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			errs.add(node, path, fmt.Sprintf("expected true or false, got %q", node.Value))
+		}
+	}
+}
+
+func kindMatches(schemaType string, node *yaml.Node) bool {
+	switch schemaType {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	default:
+		return node.Kind == yaml.ScalarNode
+	}
+}
+
+func unknownFieldMessage(name string, properties map[string]*schema) string {
+	message := fmt.Sprintf("unknown field %q", name)
+	if suggestion := closestField(name, properties); suggestion != "" {
+		message += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+	return message
+}
+
+// closestField suggests a known field for a misspelled one
+func closestField(name string, properties map[string]*schema) string {
+	names := make([]string, 0, len(properties))
+	for known := range properties {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+
+	best, bestDistance := "", 3
+	for _, known := range names {
+		if strings.EqualFold(known, name) {
+			return known
+		}
+		if d := flagutil.EditDistance(strings.ToLower(known), strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
@@ -0,0 +1,153 @@
+// Package functionspec defines the YAML function specification consumed by
+// 'nvcf function create --file' and the validation applied when loading it.
+package functionspec
+
+import "time"
+
+// Defaults applied to fields a function leaves unset. They match the defaults
+// of the 'nvcf function create' flags.
+const (
+	DefaultInferencePort             = 80
+	DefaultHealthProtocol            = "HTTP"
+	DefaultHealthPort                = 80
+	DefaultHealthTimeout             = 20 * time.Second
+	DefaultHealthStatusCode          = 200
+	DefaultHealthUri                 = "/health"
+	DefaultInstMax                   = 1
+	DefaultInstMaxRequestConcurrency = 1
+)
+
+type FunctionSpec struct {
+	FnImage   string        `yaml:"fn_image"`
+	Functions []FunctionDef `yaml:"functions"`
+}
+
+type FunctionDef struct {
+	FnName                    string      `yaml:"name"`
+	ExistingFunctionID        string      `yaml:"existingFunctionID,omitempty"`
+	InferenceURL              string      `yaml:"inferenceUrl"`
+	InferencePort             int64       `yaml:"inferencePort,omitempty"`
+	HealthUri                 string      `yaml:"healthUri,omitempty"`
+	ContainerImage            string      `yaml:"containerImage,omitempty"`
+	ContainerArgs             string      `yaml:"containerArgs,omitempty"`
+	Custom                    *bool       `yaml:"custom,omitempty"`
+	Description               string      `yaml:"description,omitempty"`
+	Streaming                 *bool       `yaml:"streaming,omitempty"`
+	Tags                      []string    `yaml:"tags,omitempty"`
+	Health                    HealthCheck `yaml:"health"`
+	InstBackend               string      `yaml:"inst_backend"`
+	InstGPUType               string      `yaml:"inst_gpu_type"`
+	InstType                  string      `yaml:"inst_type"`
+	InstMin                   int64       `yaml:"inst_min,omitempty"`
+	InstMax                   int64       `yaml:"inst_max,omitempty"`
+	InstMaxRequestConcurrency int64       `yaml:"inst_max_request_concurrency,omitempty"`
+	ContainerEnvironment      []EnvVar    `yaml:"containerEnvironment,omitempty"`
+	Models                    []ModelDef  `yaml:"models,omitempty"`
+}
+
+type HealthCheck struct {
+	Protocol           string        `yaml:"protocol,omitempty"`
+	Port               int64         `yaml:"port,omitempty"`
+	Timeout            time.Duration `yaml:"timeout,omitempty"`
+	ExpectedStatusCode int64         `yaml:"expectedStatusCode,omitempty"`
+	Uri                string        `yaml:"uri,omitempty"`
+}
+
+type EnvVar struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+}
+
+type ModelDef struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Uri     string `yaml:"uri"`
+}
+
+// ApplyDefaults fills the unset fields of every function. A function without
+// its own containerImage inherits the top-level fn_image.
+func (s *FunctionSpec) ApplyDefaults() {
+	for i := range s.Functions {
+		s.Functions[i].applyDefaults(s.FnImage)
+	}
+}
+
+func (f *FunctionDef) applyDefaults(fnImage string) {
+	if f.ContainerImage == "" {
+		f.ContainerImage = fnImage
+	}
+	if f.InferencePort == 0 {
+		f.InferencePort = DefaultInferencePort
+	}
+	// unlike the create flags, a spec has always defaulted to PREDICT_V2 and DEFAULT
+	if f.Custom == nil {
+		custom := false
+		f.Custom = &custom
+	}
+	if f.Streaming == nil {
+		streaming := false
+		f.Streaming = &streaming
+	}
+	if f.Health.Protocol == "" {
+		f.Health.Protocol = DefaultHealthProtocol
+	}
+	if f.Health.Port == 0 {
+		f.Health.Port = DefaultHealthPort
+	}
+	if f.Health.Timeout == 0 {
+		f.Health.Timeout = DefaultHealthTimeout
+	}
+	if f.Health.ExpectedStatusCode == 0 {
+		f.Health.ExpectedStatusCode = DefaultHealthStatusCode
+	}
+	if f.Health.Uri == "" {
+		f.Health.Uri = f.HealthUri
+	}
+	if f.Health.Uri == "" {
+		f.Health.Uri = DefaultHealthUri
+	}
+	if f.InstMax == 0 {
+		f.InstMax = DefaultInstMax
+	}
+	if f.InstMaxRequestConcurrency == 0 {
+		f.InstMaxRequestConcurrency = DefaultInstMaxRequestConcurrency
+	}
+}
+
+func (h *HealthCheck) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	aux := &struct {
+		Protocol           string `yaml:"protocol"`
+		Port               int64  `yaml:"port"`
+		Timeout            int    `yaml:"timeout"`
+		ExpectedStatusCode int64  `yaml:"expectedStatusCode"`
+		Uri                string `yaml:"uri"`
+	}{}
+
+	if err := unmarshal(aux); err != nil {
+		return err
+	}
+
+	h.Protocol = aux.Protocol
+	h.Port = aux.Port
+	h.Timeout = time.Duration(aux.Timeout) * time.Second
+	h.ExpectedStatusCode = aux.ExpectedStatusCode
+	h.Uri = aux.Uri
+
+	return nil
+}
+
+func (h HealthCheck) MarshalYAML() (interface{}, error) {
+	return struct {
+		Protocol           string `yaml:"protocol,omitempty"`
+		Port               int64  `yaml:"port,omitempty"`
+		Timeout            int    `yaml:"timeout,omitempty"`
+		ExpectedStatusCode int64  `yaml:"expectedStatusCode,omitempty"`
+		Uri                string `yaml:"uri,omitempty"`
+	}{
+		Protocol:           h.Protocol,
+		Port:               h.Port,
+		Timeout:            int(h.Timeout / time.Second),
+		ExpectedStatusCode: h.ExpectedStatusCode,
+		Uri:                h.Uri,
+	}, nil
+}
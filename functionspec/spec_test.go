@@ -0,0 +1,92 @@
+package functionspec
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name string
+		spec string
+		want FunctionDef
+	}{
+		{
+			name: "inherits fn_image and defaults",
+			spec: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+`,
+			want: FunctionDef{
+				FnName:         "app",
+				InferenceURL:   "/infer",
+				InferencePort:  DefaultInferencePort,
+				ContainerImage: "nvcr.io/org/app:1",
+				Custom:         &no,
+				Streaming:      &no,
+				Health: HealthCheck{
+					Protocol:           DefaultHealthProtocol,
+					Port:               DefaultHealthPort,
+					Timeout:            DefaultHealthTimeout,
+					ExpectedStatusCode: DefaultHealthStatusCode,
+					Uri:                DefaultHealthUri,
+				},
+				InstMax:                   DefaultInstMax,
+				InstMaxRequestConcurrency: DefaultInstMaxRequestConcurrency,
+			},
+		},
+		{
+			name: "own containerImage, healthUri fallback and timeout",
+			spec: `fn_image: nvcr.io/org/app:1
+functions:
+  - name: app
+    inferenceUrl: /infer
+    containerImage: nvcr.io/org/other:2
+    custom: true
+    streaming: true
+    healthUri: /ready
+    health:
+      timeout: 45
+    models:
+      - name: llama
+        version: "3"
+        uri: ngc://org/llama
+`,
+			want: FunctionDef{
+				FnName:         "app",
+				InferenceURL:   "/infer",
+				InferencePort:  DefaultInferencePort,
+				HealthUri:      "/ready",
+				ContainerImage: "nvcr.io/org/other:2",
+				Custom:         &yes,
+				Streaming:      &yes,
+				Health: HealthCheck{
+					Protocol:           DefaultHealthProtocol,
+					Port:               DefaultHealthPort,
+					Timeout:            45 * time.Second,
+					ExpectedStatusCode: DefaultHealthStatusCode,
+					Uri:                "/ready",
+				},
+				InstMax:                   DefaultInstMax,
+				InstMaxRequestConcurrency: DefaultInstMaxRequestConcurrency,
+				Models:                    []ModelDef{{Name: "llama", Version: "3", Uri: "ngc://org/llama"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := Parse("spec.yaml", []byte(tt.spec), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.ApplyDefaults()
+			if got := spec.Functions[0]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
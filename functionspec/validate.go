@@ -0,0 +1,268 @@
+package functionspec
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a problem found in a spec file, located by file, line and column.
+type ValidationError struct {
+	File    string
+	Line    int
+	Column  int
+	Path    string
+	Message string
+
+	node *yaml.Node
+}
+
+func (e ValidationError) Error() string {
+	location := fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+	if e.Path == "" {
+		return fmt.Sprintf("%s: %s", location, e.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", location, e.Path, e.Message)
+}
+
+// ValidationErrors collects every problem found in a spec file.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (e *ValidationErrors) add(node *yaml.Node, path, message string) {
+	*e = append(*e, ValidationError{Line: node.Line, Column: node.Column, Path: path, Message: message, node: node})
+}
+
+// Options control how a spec is resolved and the checks applied when loading it.
+type Options struct {
+	// Deploy requires the inst_* deployment fields of every function.
+	Deploy bool
+	// Vars take precedence over environment variables in ${VAR} references.
+	Vars map[string]string
+	// Overlays are spec files deep-merged over the base spec, in order.
+	Overlays []string
+}
+
+// document is a spec resolved from a base file and its overlays. origins maps
+// every node to the file it was read from so that errors point at the right file.
+type document struct {
+	root    *yaml.Node
+	files   []string
+	origins map[*yaml.Node]string
+}
+
+// Load reads the spec file at path, merges its overlays, expands variables,
+// validates the result and decodes it strictly.
+func Load(path string, opts Options) (*FunctionSpec, error) {
+	doc, err := resolve(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	return doc.decode(path, opts)
+}
+
+// Parse validates and decodes a single spec held in memory. Overlays in opts
+// are ignored.
+func Parse(filename string, data []byte, opts Options) (*FunctionSpec, error) {
+	doc := &document{origins: map[*yaml.Node]string{}}
+	if err := doc.add(filename, data, opts.Vars); err != nil {
+		return nil, err
+	}
+	return doc.decode(filename, opts)
+}
+
+// Render resolves the spec file at path with its overlays and variables,
+// validates it and returns the final YAML document.
+func Render(path string, opts Options) ([]byte, error) {
+	doc, err := resolve(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.validate(path, opts); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc.root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func resolve(path string, opts Options) (*document, error) {
+	doc := &document{origins: map[*yaml.Node]string{}}
+	for _, file := range append([]string{path}, opts.Overlays...) {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.add(file, data, opts.Vars); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// add parses data, expands its variables and merges it over the document
+func (d *document) add(filename string, data []byte, vars map[string]string) error {
+	var file yaml.Node
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", filename, err)
+	}
+	if file.Kind != yaml.DocumentNode || len(file.Content) == 0 {
+		return ValidationErrors{{File: filename, Line: 1, Column: 1, Message: "the spec is empty"}}
+	}
+	root := file.Content[0]
+	d.files = append(d.files, filename)
+	d.recordOrigin(root, filename)
+
+	var errs ValidationErrors
+	substituteVars(&errs, root, vars)
+	if len(errs) > 0 {
+		return d.located(errs, filename)
+	}
+
+	if d.root == nil {
+		d.root = root
+	} else {
+		d.root = mergeNodes(d.root, root)
+	}
+	return nil
+}
+
+func (d *document) recordOrigin(node *yaml.Node, filename string) {
+	d.origins[node] = filename
+	for _, child := range node.Content {
+		d.recordOrigin(child, filename)
+	}
+}
+
+// validate checks the document against the schema and the rules it cannot express,
+// then resolves the container environment of every function
+func (d *document) validate(filename string, opts Options) error {
+	var errs ValidationErrors
+	validateNode(&errs, rootSchema, rootSchema, d.root, "")
+	validateRules(&errs, d.root, opts)
+	if len(errs) == 0 {
+		d.normalizeEnv(&errs)
+	}
+	if len(errs) > 0 {
+		return d.located(errs, filename)
+	}
+	return nil
+}
+
+func (d *document) decode(filename string, opts Options) (*FunctionSpec, error) {
+	if err := d.validate(filename, opts); err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(d.root)
+	if err != nil {
+		return nil, err
+	}
+	var spec FunctionSpec
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &spec, nil
+}
+
+// located attributes each error to the file its node came from and sorts them by position
+func (d *document) located(errs ValidationErrors, filename string) ValidationErrors {
+	for i := range errs {
+		errs[i].File = filename
+		if origin, ok := d.origins[errs[i].node]; ok {
+			errs[i].File = origin
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].File != errs[j].File {
+			return d.fileIndex(errs[i].File) < d.fileIndex(errs[j].File)
+		}
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	return errs
+}
+
+func (d *document) fileIndex(filename string) int {
+	for i, file := range d.files {
+		if file == filename {
+			return i
+		}
+	}
+	return len(d.files)
+}
+
+// validateRules applies the cross-field rules that the JSON Schema cannot express
+func validateRules(errs *ValidationErrors, root *yaml.Node, opts Options) {
+	fnImage := mappingValue(root, "fn_image")
+	functions := mappingValue(root, "functions")
+	if functions == nil {
+		return
+	}
+	for i, fn := range functions.Content {
+		path := fmt.Sprintf("functions[%d]", i)
+		if isEmpty(mappingValue(fn, "containerImage")) && isEmpty(fnImage) {
+			errs.add(fn, path, "no container image: set containerImage or the top-level fn_image")
+		}
+		if opts.Deploy {
+			for _, field := range []string{"inst_backend", "inst_gpu_type", "inst_type"} {
+				if isEmpty(mappingValue(fn, field)) {
+					errs.add(fn, path, fmt.Sprintf("%s is required to deploy the function", field))
+				}
+			}
+		}
+		// inst_max is compared as ApplyDefaults will set it
+		instMin, instMax := mappingValue(fn, "inst_min"), mappingValue(fn, "inst_max")
+		var minInstances, maxInstances int64
+		if isEmpty(instMin) || instMin.Decode(&minInstances) != nil {
+			continue
+		}
+		if !isEmpty(instMax) && instMax.Decode(&maxInstances) != nil {
+			continue
+		}
+		switch {
+		case maxInstances == 0 && minInstances > DefaultInstMax:
+			errs.add(instMin, joinPath(path, "inst_min"), fmt.Sprintf("must not be greater than inst_max, which defaults to %d. Set inst_max", DefaultInstMax))
+		case maxInstances != 0 && minInstances > maxInstances:
+			errs.add(instMin, joinPath(path, "inst_min"), fmt.Sprintf("must not be greater than inst_max (%d)", maxInstances))
+		}
+	}
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func isEmpty(node *yaml.Node) bool {
+	return node == nil || node.ShortTag() == "!!null" || (node.Kind == yaml.ScalarNode && node.Value == "")
+}
@@ -0,0 +1,74 @@
+package functionspec
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		file   string
+		deploy bool
+		want   []string
+	}{
+		{
+			file: "testdata/unknown_field.yaml",
+			want: []string{
+				`testdata/unknown_field.yaml:5:5: functions[0]: unknown field "inferencPort", did you mean "inferencePort"?`,
+				`testdata/unknown_field.yaml:7:7: functions[0].health: unknown field "protocl", did you mean "protocol"?`,
+			},
+		},
+		{
+			file: "testdata/missing_required.yaml",
+			want: []string{
+				`testdata/missing_required.yaml:2:5: functions[0]: missing required field "inferenceUrl"`,
+				`testdata/missing_required.yaml:4:5: functions[1]: missing required field "name"`,
+				`testdata/missing_required.yaml:4:5: functions[1]: no container image: set containerImage or the top-level fn_image`,
+			},
+		},
+		{
+			file: "testdata/invalid_values.yaml",
+			want: []string{
+				`testdata/invalid_values.yaml:5:20: functions[0].inferencePort: must be at most 65535`,
+				`testdata/invalid_values.yaml:6:16: functions[0].streaming: expected true or false, got "yes"`,
+				`testdata/invalid_values.yaml:7:15: functions[0].inst_max: expected an integer, got "two"`,
+				`testdata/invalid_values.yaml:9:17: functions[0].health.protocol: invalid value "HTTPS", expected one of HTTP, gRPC`,
+			},
+		},
+		{
+			file: "testdata/instances.yaml",
+			want: []string{
+				`testdata/instances.yaml:5:15: functions[0].inst_min: must not be greater than inst_max, which defaults to 1. Set inst_max`,
+				`testdata/instances.yaml:8:15: functions[1].inst_min: must not be greater than inst_max (2)`,
+			},
+		},
+		{
+			file:   "testdata/deploy.yaml",
+			deploy: true,
+			want: []string{
+				`testdata/deploy.yaml:2:5: functions[0]: inst_gpu_type is required to deploy the function`,
+				`testdata/deploy.yaml:2:5: functions[0]: inst_type is required to deploy the function`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := Load(tt.file, Options{Deploy: tt.deploy})
+			var validationErrs ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				t.Fatalf("got %v, want validation errors", err)
+			}
+			if got, want := validationErrs.Error(), strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadDeployFieldsOnlyRequiredToDeploy(t *testing.T) {
+	if _, err := Load("testdata/deploy.yaml", Options{}); err != nil {
+		t.Errorf("got %v, want no error without Deploy", err)
+	}
+}
@@ -0,0 +1,88 @@
+package functionspec
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseVars parses KEY=VALUE pairs such as the values of a --var flag.
+func ParseVars(pairs []string) (map[string]string, error) {
+	vars := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid variable %q, expected KEY=VALUE", pair)
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// substituteVars expands ${VAR} and ${VAR:-default} in every scalar value of the
+// tree. Values come from vars first, then from the environment. $${ is an escaped ${.
+func substituteVars(errs *ValidationErrors, node *yaml.Node, vars map[string]string) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			substituteVars(errs, child, vars)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			substituteVars(errs, node.Content[i], vars)
+		}
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "${") {
+			return
+		}
+		value, err := expand(node.Value, vars)
+		if err != nil {
+			errs.add(node, "", err.Error())
+			return
+		}
+		node.Value = value
+		if node.Style == 0 {
+			// let plain scalars resolve to ints and bools again once expanded
+			node.Tag = ""
+		}
+	}
+}
+
+func expand(s string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if start > 0 && s[start-1] == '$' {
+			b.WriteString(s[:start-1])
+			b.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		b.WriteString(s[:start])
+
+		name, fallback, hasFallback := strings.Cut(s[start+2:start+end], ":-")
+		value, ok := vars[name]
+		if !ok {
+			value, ok = os.LookupEnv(name)
+		}
+		switch {
+		case ok && (value != "" || !hasFallback):
+			b.WriteString(value)
+		case hasFallback:
+			b.WriteString(fallback)
+		default:
+			return "", fmt.Errorf("variable %s is not set; set it in the environment, pass --var %s=<value> or use ${%s:-default}", name, name, name)
+		}
+		s = s[start+end+1:]
+	}
+}
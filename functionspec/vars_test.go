@@ -0,0 +1,59 @@
+package functionspec
+
+import (
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	t.Setenv("NVCF_TEST_TAG", "from-env")
+	t.Setenv("NVCF_TEST_REGION", "us")
+	t.Setenv("NVCF_TEST_EMPTY", "")
+	vars := map[string]string{"NVCF_TEST_TAG": "from-var", "NVCF_TEST_PORT": "8000"}
+
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{name: "--var wins over the environment", in: "app:${NVCF_TEST_TAG}", want: "app:from-var"},
+		{name: "--var wins over the default", in: "${NVCF_TEST_TAG:-fallback}", want: "from-var"},
+		{name: "environment without --var", in: "${NVCF_TEST_REGION}-${NVCF_TEST_PORT}", want: "us-8000"},
+		{name: "empty without default", in: "x${NVCF_TEST_EMPTY}x", want: "xx"},
+		{name: "default when unset", in: "${NVCF_TEST_UNSET:-1.0}", want: "1.0"},
+		{name: "default when empty", in: "${NVCF_TEST_EMPTY:-1.0}", want: "1.0"},
+		{name: "empty default", in: "x${NVCF_TEST_UNSET:-}x", want: "xx"},
+		{name: "escaped reference", in: "$${NVCF_TEST_TAG} ${NVCF_TEST_TAG}", want: "${NVCF_TEST_TAG} from-var"},
+		{name: "unset without default", in: "${NVCF_TEST_UNSET}", wantErr: true},
+		{name: "unterminated", in: "${NVCF_TEST_TAG", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := expand(tt.in, vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseVarsTypes(t *testing.T) {
+	spec, err := Parse("spec.yaml", []byte(`fn_image: nvcr.io/org/app:${TAG}
+functions:
+  - name: app
+    inferenceUrl: /infer
+    inferencePort: ${PORT}
+    streaming: ${STREAMING:-false}
+    description: "${PORT}"
+`), Options{Vars: map[string]string{"TAG": "1.2.0", "PORT": "8000", "STREAMING": "true"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fn := spec.Functions[0]
+	if spec.FnImage != "nvcr.io/org/app:1.2.0" || fn.InferencePort != 8000 || fn.Streaming == nil || !*fn.Streaming || fn.Description != "8000" {
+		t.Errorf("got fn_image %q, function %+v", spec.FnImage, fn)
+	}
+}
@@ -6,9 +6,11 @@ import (
 
 	"github.com/brevdev/nvcf/cmd"
 	"github.com/brevdev/nvcf/cmd/auth"
+	"github.com/brevdev/nvcf/cmd/cost"
 	"github.com/brevdev/nvcf/cmd/function"
 	"github.com/brevdev/nvcf/cmd/gpu"
 	"github.com/brevdev/nvcf/cmd/preflight"
+	"github.com/brevdev/nvcf/cmd/spec"
 	"github.com/brevdev/nvcf/config"
 	"github.com/brevdev/nvcf/output"
 	"github.com/spf13/cobra"
@@ -30,6 +32,7 @@ func run() error {
 Environment variables:
   NVCF_BETA - Set to true to enable beta features
   NVCF_SHOW_DOCS_CMD - Set to true to show the docs command
+  NVCF_PRICING_FILE - Pricing table used by the cost command and --max-hourly-cost. Default is ~/.nvcf/pricing.yaml
 `,
 		SilenceErrors:     true,
 		PersistentPreRunE: preRunAuthCheck,
@@ -48,6 +51,8 @@ Environment variables:
 	rootCmd.PersistentFlags().Bool("no-color", false, "Disable color output")
 	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Suppress non-error output")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Enable verbose output and show underlying API calls")
+	rootCmd.PersistentFlags().Bool("no-input", false, "Never prompt; fail when a choice is ambiguous. Also set by NVCF_NO_INPUT=1 and when stdin is not a terminal")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Print the sequence of API requests. Those that create, deploy, update, stop and delete are not sent")
 
 	// Add commands
 	rootCmd.AddCommand(function.FunctionCmd())
@@ -59,6 +64,8 @@ Environment variables:
 	// rootCmd.AddCommand(cmd.ClusterGroupCmd())
 	// rootCmd.AddCommand(cmd.ConfigCmd())
 	rootCmd.AddCommand(preflight.PreflightCmd())
+	rootCmd.AddCommand(spec.SpecCmd())
+	rootCmd.AddCommand(cost.CostCmd())
 	rootCmd.AddCommand(cmd.DocsCmd())
 
 	// // Enable command auto-completion
@@ -48,8 +48,7 @@ func printJSON(cmd *cobra.Command, data interface{}) error {
 // TODO: Implement secure input for secrets
 func Prompt(message string, isSecret bool) string {
 	Type(message)
-	var input string
-	_, err := fmt.Scanln(&input)
+	input, err := ReadLine("")
 	if err != nil {
 		return ""
 	}
@@ -0,0 +1,167 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Progress shows one row per task while tasks run concurrently. On a terminal
+// the rows are redrawn in place; otherwise every status change is printed as
+// its own line. Nothing is printed with --quiet or --json.
+type Progress struct {
+	mu     sync.Mutex
+	out    io.Writer
+	labels []string
+	states []progressState
+	live   bool
+	silent bool
+	drawn  int
+	frame  int
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+type progressState struct {
+	status   string
+	finished bool
+	failed   bool
+	skipped  bool
+}
+
+// NewProgress creates a progress display with a row for each label
+func NewProgress(cmd *cobra.Command, labels []string) *Progress {
+	out := cmd.OutOrStdout()
+	return &Progress{
+		out:    out,
+		labels: labels,
+		states: make([]progressState, len(labels)),
+		live:   IsTerminalWriter(out) && os.Getenv("CI") != "true",
+		silent: isQuiet(cmd) || isJSON(cmd),
+		done:   make(chan struct{}),
+	}
+}
+
+// IsTerminal reports whether f is an interactive terminal
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// IsTerminalWriter reports whether w writes to an interactive terminal. Writers
+// that are not files, such as buffers, are not terminals.
+func IsTerminalWriter(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	return ok && IsTerminal(f)
+}
+
+// Start begins redrawing the rows
+func (p *Progress) Start() {
+	if p.silent || !p.live {
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.frame++
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Update sets the status of a running row
+func (p *Progress) Update(row int, status string) {
+	p.set(row, progressState{status: status})
+}
+
+// Finish marks a row as done, failed when err is not nil
+func (p *Progress) Finish(row int, status string, err error) {
+	if err != nil {
+		status = fmt.Sprintf("%s: %v", status, err)
+	}
+	p.set(row, progressState{status: status, finished: true, failed: err != nil})
+}
+
+// Skip marks a row as done without having run
+func (p *Progress) Skip(row int, status string) {
+	p.set(row, progressState{status: status, finished: true, skipped: true})
+}
+
+// Stop draws the final state of every row and stops redrawing
+func (p *Progress) Stop() {
+	close(p.done)
+	p.wg.Wait()
+	if !p.silent && p.live {
+		p.mu.Lock()
+		p.draw()
+		p.mu.Unlock()
+	}
+}
+
+func (p *Progress) set(row int, state progressState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.states[row] == state {
+		return
+	}
+	p.states[row] = state
+	if !p.silent && !p.live {
+		fmt.Fprintln(p.out, p.line(row))
+	}
+}
+
+func (p *Progress) draw() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.out, "\033[%dA", p.drawn)
+	}
+	for row := range p.labels {
+		fmt.Fprintf(p.out, "\r\033[2K%s\n", p.line(row))
+	}
+	p.drawn = len(p.labels)
+}
+
+func (p *Progress) line(row int) string {
+	state := p.states[row]
+	frames := spinner.CharSets[4]
+	marker := frames[p.frame%len(frames)]
+	switch {
+	case state.failed:
+		marker = color.New(color.FgRed).Sprint("✗")
+	case state.skipped:
+		marker = "-"
+	case state.finished:
+		marker = color.New(color.FgGreen).Sprint("✓")
+	case state.status == "":
+		marker = " "
+		state.status = "waiting"
+	case !p.live:
+		marker = "•"
+	}
+	return fmt.Sprintf("%s %-*s  %s", marker, p.labelWidth(), p.labels[row], state.status)
+}
+
+func (p *Progress) labelWidth() int {
+	width := 0
+	for _, label := range p.labels {
+		if len(label) > width {
+			width = len(label)
+		}
+	}
+	return width
+}
@@ -0,0 +1,30 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestProgressToWriterIsNotLive(t *testing.T) {
+	var out bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.Flags().Bool("json", false, "")
+	cmd.Flags().Bool("quiet", false, "")
+	cmd.SetOut(&out)
+
+	progress := NewProgress(cmd, []string{"echo"})
+	progress.Start()
+	progress.Update(0, "creating")
+	progress.Finish(0, "created", nil)
+	progress.Stop()
+
+	if strings.Contains(out.String(), "\033[") {
+		t.Errorf("progress written to a buffer contains terminal escape codes: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "creating") || !strings.Contains(out.String(), "created") {
+		t.Errorf("progress lines missing: %q", out.String())
+	}
+}
@@ -0,0 +1,136 @@
+package output
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tmc/nvcf-go"
+)
+
+// strategies of the --select flag
+const (
+	SelectLatest       = "latest"
+	SelectNewestActive = "newest-active"
+)
+
+// SelectFlagUsage is the help of the --select flag of commands that pick a function version
+const SelectFlagUsage = "How to pick the version when there are several and --version-id is not set: latest (most recently created) or newest-active (most recently created ACTIVE version). Without it, you are prompted"
+
+// CanPrompt reports whether cmd may ask for input: stdin and stdout are
+// terminals and neither --no-input, NVCF_NO_INPUT nor --json is set.
+func CanPrompt(cmd *cobra.Command) bool {
+	if noInput, _ := cmd.Flags().GetBool("no-input"); noInput || envNoInput() || isJSON(cmd) {
+		return false
+	}
+	return IsTerminal(os.Stdin) && IsTerminal(os.Stdout)
+}
+
+func envNoInput() bool {
+	v := strings.TrimSpace(os.Getenv("NVCF_NO_INPUT"))
+	return v != "" && v != "0" && !strings.EqualFold(v, "false")
+}
+
+// SelectVersion picks the version of a function to act on among candidates.
+// A single candidate is picked as is. Otherwise the --select strategy decides
+// or, without one, the user is prompted. When prompting is not possible, the
+// error lists the candidates.
+func SelectVersion(cmd *cobra.Command, candidates []nvcf.ListFunctionsResponseFunction, action string) (string, error) {
+	strategy, _ := cmd.Flags().GetString("select")
+	switch {
+	case len(candidates) == 0:
+		return "", Error(cmd, fmt.Sprintf("No versions to %s", action), nil)
+	case strategy != "":
+		return selectByStrategy(cmd, candidates, strategy, action)
+	case len(candidates) == 1:
+		return candidates[0].VersionID, nil
+	case !CanPrompt(cmd):
+		return "", Error(cmd, fmt.Sprintf("Multiple versions found and input is not possible. Pass --version-id or --select %s|%s to choose the version to %s. Candidates:\n%s",
+			SelectLatest, SelectNewestActive, action, describeVersions(candidates)), nil)
+	}
+
+	Info(cmd, fmt.Sprintf("Multiple versions found. Please select a version to %s:", action))
+	fmt.Println(describeVersions(candidates))
+	answer, err := ReadLine(fmt.Sprintf("Enter version-id to %s: ", action))
+	if err != nil {
+		return "", Error(cmd, "No version-id entered", err)
+	}
+	for _, candidate := range candidates {
+		if candidate.VersionID == answer {
+			return answer, nil
+		}
+	}
+	return "", Error(cmd, fmt.Sprintf("%q is not one of the versions listed", answer), nil)
+}
+
+func selectByStrategy(cmd *cobra.Command, candidates []nvcf.ListFunctionsResponseFunction, strategy, action string) (string, error) {
+	sorted := make([]nvcf.ListFunctionsResponseFunction, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+	switch strategy {
+	case SelectLatest:
+		Info(cmd, fmt.Sprintf("Selected version %s, the latest version", sorted[0].VersionID))
+		return sorted[0].VersionID, nil
+	case SelectNewestActive:
+		for _, version := range sorted {
+			if version.Status == nvcf.ListFunctionsResponseFunctionsStatusActive {
+				Info(cmd, fmt.Sprintf("Selected version %s, the newest active version", version.VersionID))
+				return version.VersionID, nil
+			}
+		}
+		return "", Error(cmd, fmt.Sprintf("None of the versions to %s is ACTIVE. Candidates:\n%s", action, describeVersions(candidates)), nil)
+	}
+	return "", Error(cmd, fmt.Sprintf("Invalid --select %q, expected %s or %s", strategy, SelectLatest, SelectNewestActive), nil)
+}
+
+func describeVersions(versions []nvcf.ListFunctionsResponseFunction) string {
+	lines := make([]string, 0, len(versions))
+	for _, version := range versions {
+		lines = append(lines, fmt.Sprintf("  %s  %-9s  created %s", version.VersionID, version.Status, version.CreatedAt.Format(time.RFC3339)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// stdin is shared by every ReadLine so that input buffered by one call is not
+// lost to the next
+var stdin = bufio.NewReader(os.Stdin)
+
+// ReadLine prints message and reads a line from stdin. It fails with
+// io.ErrUnexpectedEOF when stdin is closed before a line is entered.
+func ReadLine(message string) (string, error) {
+	fmt.Print(message)
+	line, err := stdin.ReadString('\n')
+	if errors.Is(err, io.EOF) && strings.TrimSpace(line) == "" {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// Confirm asks the user to confirm an action described by message. --yes
+// confirms without asking; when prompting is not possible, --yes is required.
+func Confirm(cmd *cobra.Command, message string) (bool, error) {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return true, nil
+	}
+	if !CanPrompt(cmd) {
+		return false, Error(cmd, fmt.Sprintf("%s\nInput is not possible to confirm this. Pass --yes to proceed", message), nil)
+	}
+	fmt.Println(message)
+	answer, err := ReadLine("Proceed? [y/N]: ")
+	if err != nil {
+		return false, nil
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
@@ -0,0 +1,24 @@
+package output
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestPromptSharesStdin(t *testing.T) {
+	t.Setenv("CI", "true")
+	saved := stdin
+	t.Cleanup(func() { stdin = saved })
+	stdin = bufio.NewReader(strings.NewReader("y\n nvapi-key \n"))
+
+	if answer, err := ReadLine("Proceed? "); err != nil || answer != "y" {
+		t.Fatalf("got %q, %v, want y", answer, err)
+	}
+	if key := Prompt("API key: ", true); key != "nvapi-key" {
+		t.Errorf("got %q, want the line buffered by the previous read", key)
+	}
+	if key := Prompt("API key: ", true); key != "" {
+		t.Errorf("got %q, want nothing once stdin is closed", key)
+	}
+}
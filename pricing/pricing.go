@@ -0,0 +1,158 @@
+// Package pricing reads the local table of GPU instance prices used to
+// estimate what deployments cost. NVCF does not publish prices, so the table
+// holds whatever an org pays.
+package pricing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HoursPerMonth is the average number of hours in a month
+const HoursPerMonth = 730
+
+// Example is a pricing table to start from
+const Example = `# Prices per instance per hour. Without an instanceType, a price applies to
+# every instance type of the GPU. Prices of an org take precedence over the defaults.
+currency: USD
+default:
+  - gpu: H100
+    hourly: 4.50
+  - gpu: L40
+    instanceType: gl40_1.br20_2xlarge
+    hourly: 1.20
+orgs:
+  my-org-id:
+    - gpu: H100
+      hourly: 3.90
+`
+
+// Table holds the hourly price of an instance per GPU and instance type, by default and per org
+type Table struct {
+	Currency string             `yaml:"currency,omitempty"`
+	Default  []Price            `yaml:"default,omitempty"`
+	Orgs     map[string][]Price `yaml:"orgs,omitempty"`
+}
+
+// Price is what one instance costs per hour
+type Price struct {
+	GPU          string  `yaml:"gpu"`
+	InstanceType string  `yaml:"instanceType,omitempty"`
+	Hourly       float64 `yaml:"hourly"`
+}
+
+// Estimate is what a deployment costs at its min and max instances
+type Estimate struct {
+	Currency       string  `json:"currency"`
+	InstanceHourly float64 `json:"instanceHourly"`
+	MinHourly      float64 `json:"minHourly"`
+	MaxHourly      float64 `json:"maxHourly"`
+	MinMonthly     float64 `json:"minMonthly"`
+	MaxMonthly     float64 `json:"maxMonthly"`
+}
+
+// Path is where the pricing table is read from: $NVCF_PRICING_FILE, or ~/.nvcf/pricing.yaml
+func Path() (string, error) {
+	if path := os.Getenv("NVCF_PRICING_FILE"); path != "" {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".nvcf", "pricing.yaml"), nil
+}
+
+// Load reads the pricing table. A missing table is reported with an error
+// satisfying errors.Is(err, os.ErrNotExist).
+func Load() (*Table, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse decodes and checks a pricing table held in memory
+func Parse(filename string, data []byte) (*Table, error) {
+	var table Table
+	decoder := yaml.NewDecoder(strings.NewReader(string(data)))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&table); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	check := func(section string, prices []Price) error {
+		for i, price := range prices {
+			if price.GPU == "" {
+				return fmt.Errorf("%s: %s[%d].gpu is required", filename, section, i)
+			}
+			if price.Hourly < 0 {
+				return fmt.Errorf("%s: %s[%d].hourly must not be negative", filename, section, i)
+			}
+		}
+		return nil
+	}
+	if err := check("default", table.Default); err != nil {
+		return nil, err
+	}
+	for org, prices := range table.Orgs {
+		if err := check("orgs."+org, prices); err != nil {
+			return nil, err
+		}
+	}
+	if table.Currency == "" {
+		table.Currency = "USD"
+	}
+	return &table, nil
+}
+
+// Lookup finds the price of an instance for an org. The org's prices are
+// searched before the defaults, and an exact instance type before the GPU alone.
+func (t *Table) Lookup(orgID, gpu, instanceType string) (Price, bool) {
+	for _, prices := range [][]Price{t.Orgs[orgID], t.Default} {
+		var gpuOnly *Price
+		for i, price := range prices {
+			if !strings.EqualFold(price.GPU, gpu) {
+				continue
+			}
+			if price.InstanceType == "" {
+				if gpuOnly == nil {
+					gpuOnly = &prices[i]
+				}
+			} else if strings.EqualFold(price.InstanceType, instanceType) {
+				return price, true
+			}
+		}
+		if gpuOnly != nil {
+			return *gpuOnly, true
+		}
+	}
+	return Price{}, false
+}
+
+// Estimate prices a deployment of an org at its min and max instances
+func (t *Table) Estimate(orgID, gpu, instanceType string, minInstances, maxInstances int64) (Estimate, error) {
+	price, ok := t.Lookup(orgID, gpu, instanceType)
+	if !ok {
+		if instanceType == "" {
+			return Estimate{}, fmt.Errorf("the pricing table has no price for GPU %s", gpu)
+		}
+		return Estimate{}, fmt.Errorf("the pricing table has no price for GPU %s, instance type %s", gpu, instanceType)
+	}
+	return Estimate{
+		Currency:       t.Currency,
+		InstanceHourly: price.Hourly,
+		MinHourly:      price.Hourly * float64(minInstances),
+		MaxHourly:      price.Hourly * float64(maxInstances),
+		MinMonthly:     price.Hourly * float64(minInstances) * HoursPerMonth,
+		MaxMonthly:     price.Hourly * float64(maxInstances) * HoursPerMonth,
+	}, nil
+}
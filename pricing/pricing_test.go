@@ -0,0 +1,108 @@
+package pricing
+
+import (
+	"strings"
+	"testing"
+)
+
+const table = `currency: EUR
+default:
+  - gpu: H100
+    hourly: 4.50
+  - gpu: L40
+    hourly: 1.50
+  - gpu: L40
+    instanceType: gl40_1.br20_2xlarge
+    hourly: 1.20
+orgs:
+  my-org:
+    - gpu: h100
+      hourly: 3.90
+    - gpu: L40
+      instanceType: gl40_2.br20_4xlarge
+      hourly: 2.00
+`
+
+func TestLookup(t *testing.T) {
+	prices, err := Parse("pricing.yaml", []byte(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		org          string
+		gpu          string
+		instanceType string
+		want         float64
+		wantOK       bool
+	}{
+		{name: "default", gpu: "H100", instanceType: "any", want: 4.50, wantOK: true},
+		{name: "the org's price takes precedence over the default", org: "my-org", gpu: "H100", instanceType: "any", want: 3.90, wantOK: true},
+		{name: "another org gets the default", org: "other-org", gpu: "H100", want: 4.50, wantOK: true},
+		{name: "the instance type takes precedence over the GPU alone", gpu: "L40", instanceType: "gl40_1.br20_2xlarge", want: 1.20, wantOK: true},
+		{name: "the GPU alone for other instance types", gpu: "L40", instanceType: "gl40_4.br20_8xlarge", want: 1.50, wantOK: true},
+		{name: "names are case-insensitive", gpu: "l40", instanceType: "GL40_1.BR20_2XLARGE", want: 1.20, wantOK: true},
+		{name: "the org's instance type", org: "my-org", gpu: "L40", instanceType: "gl40_2.br20_4xlarge", want: 2.00, wantOK: true},
+		{name: "the default GPU price when the org only prices another instance type", org: "my-org", gpu: "L40", instanceType: "gl40_1.br20_2xlarge", want: 1.20, wantOK: true},
+		{name: "unknown GPU", org: "my-org", gpu: "A100"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, ok := prices.Lookup(tt.org, tt.gpu, tt.instanceType)
+			if ok != tt.wantOK || price.Hourly != tt.want {
+				t.Errorf("got %.2f, %v, want %.2f, %v", price.Hourly, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	prices, err := Parse("pricing.yaml", []byte(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	estimate, err := prices.Estimate("my-org", "H100", "", 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Estimate{Currency: "EUR", InstanceHourly: 3.90, MinHourly: 3.90, MaxHourly: 15.60, MinMonthly: 3.90 * HoursPerMonth, MaxMonthly: 15.60 * HoursPerMonth}
+	if estimate != want {
+		t.Errorf("got %+v, want %+v", estimate, want)
+	}
+
+	if _, err := prices.Estimate("", "A100", "a100_1x", 0, 1); err == nil || !strings.Contains(err.Error(), "no price for GPU A100, instance type a100_1x") {
+		t.Errorf("got %v, want no price for A100", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	prices, err := Parse("pricing.yaml", []byte("default:\n  - gpu: H100\n    hourly: 4.50\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prices.Currency != "USD" {
+		t.Errorf("got currency %q, want USD by default", prices.Currency)
+	}
+	if _, err := Parse("pricing.yaml", []byte(Example)); err != nil {
+		t.Errorf("the example does not parse: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "unknown field", data: "default:\n  - gpu: H100\n    hourley: 4.50\n", want: "field hourley not found"},
+		{name: "unknown top-level field", data: "currencies: USD\n", want: "field currencies not found"},
+		{name: "missing GPU", data: "default:\n  - hourly: 4.50\n", want: "pricing.yaml: default[0].gpu is required"},
+		{name: "negative price", data: "orgs:\n  my-org:\n    - gpu: H100\n      hourly: -1\n", want: "pricing.yaml: orgs.my-org[0].hourly must not be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("pricing.yaml", []byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s is not available: %v", name, err)
+	}
+	return location
+}
+
+func TestCronNext(t *testing.T) {
+	newYork := mustLocation(t, "America/New_York")
+	havana := mustLocation(t, "America/Havana")
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "steps", expr: "*/15 * * * *", from: utc(5, 1, 10, 7), want: utc(5, 1, 10, 15)},
+		{name: "stepped range", expr: "10-40/20 * * * *", from: utc(5, 1, 10, 31), want: utc(5, 1, 11, 10)},
+		{name: "strictly after", expr: "0 10 * * *", from: utc(5, 1, 10, 0).Add(30 * time.Second), want: utc(5, 2, 10, 0)},
+		{name: "weekdays skip the weekend", expr: "0 9 * * 1-5", from: utc(5, 3, 10, 0), want: utc(5, 6, 9, 0)},
+		{name: "names", expr: "0 0 * jun-aug SAT", from: utc(5, 1, 0, 0), want: utc(6, 1, 0, 0)},
+		{name: "7 is Sunday", expr: "0 0 * * 7", from: utc(5, 1, 0, 0), want: utc(5, 5, 0, 0)},
+		{name: "range ending with 7", expr: "0 0 * * 6-7", from: utc(5, 5, 0, 0), want: utc(5, 11, 0, 0)},
+		{name: "day of month or day of week: the weekday comes first", expr: "0 0 13 * 5", from: utc(5, 1, 0, 0), want: utc(5, 3, 0, 0)},
+		{name: "day of month or day of week: the day of month comes first", expr: "0 0 13 * 5", from: utc(5, 11, 0, 0), want: utc(5, 13, 0, 0)},
+		{name: "day of month with any weekday", expr: "0 0 13 * *", from: utc(5, 1, 0, 0), want: utc(5, 13, 0, 0)},
+		{name: "leap day", expr: "0 0 29 2 *", from: utc(3, 1, 0, 0), want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{name: "never matches", expr: "0 0 30 2 *", from: utc(1, 1, 0, 0)},
+		{name: "@hourly", expr: "@hourly", from: utc(5, 1, 10, 7), want: utc(5, 1, 11, 0)},
+		{name: "@daily", expr: "@daily", from: utc(5, 1, 10, 7), want: utc(5, 2, 0, 0)},
+		{name: "@weekly", expr: "@weekly", from: utc(5, 1, 10, 7), want: utc(5, 5, 0, 0)},
+		{name: "@monthly", expr: "@monthly", from: utc(5, 1, 10, 7), want: utc(6, 1, 0, 0)},
+		{name: "@yearly", expr: "@yearly", from: utc(5, 1, 10, 7), want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{
+			name: "a time in the spring-forward gap is skipped",
+			expr: "30 2 * * *",
+			from: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 11, 2, 30, 0, 0, newYork),
+		},
+		{
+			name: "the hour after the spring-forward gap",
+			expr: "0 3 * * *",
+			from: time.Date(2024, 3, 10, 0, 0, 0, 0, newYork),
+			want: time.Date(2024, 3, 10, 3, 0, 0, 0, newYork),
+		},
+		{
+			name: "the day after a fall-back day is not shortened",
+			expr: "0 0 * * *",
+			from: time.Date(2024, 11, 3, 0, 0, 0, 0, newYork),
+			want: time.Date(2024, 11, 4, 0, 0, 0, 0, newYork),
+		},
+		{
+			name: "a day starting in the spring-forward gap",
+			expr: "0 12 11 3 *",
+			from: time.Date(2024, 3, 9, 12, 0, 0, 0, havana),
+			want: time.Date(2024, 3, 11, 12, 0, 0, 0, havana),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cron.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronPrev(t *testing.T) {
+	havana := mustLocation(t, "America/Havana")
+	utc := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{name: "at or before", expr: "0 10 * * *", from: utc(5, 1, 10, 0).Add(30 * time.Second), want: utc(5, 1, 10, 0)},
+		{name: "steps", expr: "*/15 * * * *", from: utc(5, 1, 10, 7), want: utc(5, 1, 10, 0)},
+		{name: "weekdays skip the weekend", expr: "0 9 * * 1-5", from: utc(5, 6, 8, 0), want: utc(5, 3, 9, 0)},
+		{name: "7 is Sunday", expr: "0 0 * * 7", from: utc(5, 8, 0, 0), want: utc(5, 5, 0, 0)},
+		{name: "day of month or day of week", expr: "0 0 1 * 1", from: utc(5, 12, 0, 0), want: utc(5, 6, 0, 0)},
+		{name: "@monthly", expr: "@monthly", from: utc(5, 12, 0, 0), want: utc(5, 1, 0, 0)},
+		{name: "never matched", expr: "0 0 31 4 *", from: utc(5, 1, 0, 0)},
+		{
+			name: "across a day starting in the spring-forward gap",
+			expr: "0 12 9 3 *",
+			from: time.Date(2024, 3, 10, 12, 0, 0, 0, havana),
+			want: time.Date(2024, 3, 9, 12, 0, 0, 0, havana),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cron.Prev(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Prev(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * * MON-XYZ",
+		"@every 5m",
+	} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Package schedule defines the YAML scaling schedule consumed by
+// 'nvcf function schedule run': cron expressions mapped to the min and max
+// instances of function deployments.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a scaling schedule
+type File struct {
+	// Timezone the cron expressions are evaluated in, such as America/Los_Angeles. Defaults to the local timezone.
+	Timezone  string  `yaml:"timezone,omitempty"`
+	Schedules []Entry `yaml:"schedules"`
+
+	location *time.Location
+}
+
+// Entry scales the deployment of a function version. Without a version, every
+// ACTIVE version of the function is scaled. GPU and InstanceType, if set,
+// restrict the deployment specifications that are scaled.
+type Entry struct {
+	FunctionID   string   `yaml:"functionId"`
+	VersionID    string   `yaml:"versionId,omitempty"`
+	GPU          string   `yaml:"gpu,omitempty"`
+	InstanceType string   `yaml:"instanceType,omitempty"`
+	Windows      []Window `yaml:"windows"`
+}
+
+// Window sets the min and max instances from each time its cron expression
+// matches until another window of the same entry takes over
+type Window struct {
+	Cron         string `yaml:"cron"`
+	MinInstances int64  `yaml:"minInstances"`
+	MaxInstances int64  `yaml:"maxInstances"`
+
+	cron *Cron
+}
+
+// Load reads and validates the schedule file at path
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse validates and decodes a schedule held in memory
+func Parse(filename string, data []byte) (*File, error) {
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s: %s", filename, path, fmt.Sprintf(format, args...)))
+	}
+	file.location = time.Local
+	if file.Timezone != "" {
+		location, err := time.LoadLocation(file.Timezone)
+		if err != nil {
+			fail("timezone", "unknown timezone %q", file.Timezone)
+		}
+		file.location = location
+	}
+	if len(file.Schedules) == 0 {
+		fail("schedules", "at least one schedule is required")
+	}
+	for i := range file.Schedules {
+		entry := &file.Schedules[i]
+		path := fmt.Sprintf("schedules[%d]", i)
+		if entry.FunctionID == "" {
+			fail(path+".functionId", "is required")
+		}
+		if len(entry.Windows) == 0 {
+			fail(path+".windows", "at least one window is required")
+		}
+		for j := range entry.Windows {
+			window := &entry.Windows[j]
+			windowPath := fmt.Sprintf("%s.windows[%d]", path, j)
+			cron, err := ParseCron(window.Cron)
+			if err != nil {
+				fail(windowPath+".cron", "%v", err)
+			}
+			window.cron = cron
+			if window.MinInstances < 0 || window.MaxInstances < 1 || window.MinInstances > window.MaxInstances {
+				fail(windowPath, "minInstances %d and maxInstances %d must satisfy 0 <= minInstances <= maxInstances and maxInstances >= 1", window.MinInstances, window.MaxInstances)
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &file, nil
+}
+
+// Location is the timezone the cron expressions are evaluated in
+func (f *File) Location() *time.Location {
+	return f.location
+}
+
+// Next returns the first window boundary of any entry after t, or the zero time if there is none
+func (f *File) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, entry := range f.Schedules {
+		for _, window := range entry.Windows {
+			at := window.cron.Next(t.In(f.location))
+			if !at.IsZero() && (next.IsZero() || at.Before(next)) {
+				next = at
+			}
+		}
+	}
+	return next
+}
+
+// Active returns the window of the entry in effect at t, which is the one that
+// matched most recently, and when it started. Boundaries missed while nothing
+// was running are thereby caught up on. It reports false if no window matched
+// within five years.
+func (e Entry) Active(t time.Time, location *time.Location) (Window, time.Time, bool) {
+	var active Window
+	var since time.Time
+	for _, window := range e.Windows {
+		at := window.cron.Prev(t.In(location))
+		if !at.IsZero() && (since.IsZero() || !at.Before(since)) {
+			active, since = window, at
+		}
+	}
+	return active, since, !since.IsZero()
+}
+
+func (e Entry) String() string {
+	if e.VersionID == "" {
+		return e.FunctionID
+	}
+	return e.FunctionID + "/" + e.VersionID
+}
@@ -0,0 +1,122 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const businessHours = `timezone: UTC
+schedules:
+  - functionId: fid
+    windows:
+      - cron: "0 8 * * 1-5"
+        minInstances: 2
+        maxInstances: 4
+      - cron: "0 20 * * 1-5"
+        minInstances: 0
+        maxInstances: 1
+  - functionId: batch
+    versionId: vid
+    windows:
+      - cron: "30 1 * * *"
+        minInstances: 1
+        maxInstances: 8
+`
+
+func TestEntryActive(t *testing.T) {
+	file, err := Parse("schedule.yaml", []byte(businessHours))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := file.Schedules[0]
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name      string
+		at        time.Time
+		wantMax   int64
+		wantSince time.Time
+	}{
+		{name: "during the day", at: at(1, 12, 0), wantMax: 4, wantSince: at(1, 8, 0)},
+		{name: "at the boundary", at: at(1, 20, 0), wantMax: 1, wantSince: at(1, 20, 0)},
+		{name: "just before the boundary", at: at(1, 19, 59), wantMax: 4, wantSince: at(1, 8, 0)},
+		{name: "the night", at: at(2, 3, 0), wantMax: 1, wantSince: at(1, 20, 0)},
+		{name: "missed windows are caught up on over the weekend", at: at(4, 12, 0), wantMax: 1, wantSince: at(3, 20, 0)},
+		{name: "monday before the first window", at: at(6, 7, 59), wantMax: 1, wantSince: at(3, 20, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			window, since, ok := entry.Active(tt.at, file.Location())
+			if !ok || window.MaxInstances != tt.wantMax || !since.Equal(tt.wantSince) {
+				t.Errorf("got max %d since %s (%v), want max %d since %s", window.MaxInstances, since, ok, tt.wantMax, tt.wantSince)
+			}
+		})
+	}
+
+	never := Entry{FunctionID: "fid", Windows: []Window{{Cron: "0 0 30 2 *", MaxInstances: 1}}}
+	never.Windows[0].cron, _ = ParseCron(never.Windows[0].Cron)
+	if _, _, ok := never.Active(at(1, 0, 0), time.UTC); ok {
+		t.Error("got an active window for an expression that never matches")
+	}
+}
+
+func TestEntryActiveTimezone(t *testing.T) {
+	losAngeles := mustLocation(t, "America/Los_Angeles")
+	file, err := Parse("schedule.yaml", []byte(strings.Replace(businessHours, "timezone: UTC", "timezone: America/Los_Angeles", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 16:00 UTC is 09:00 in Los Angeles, after the 08:00 window started there
+	window, since, ok := file.Schedules[0].Active(time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC), file.Location())
+	if want := time.Date(2024, 5, 1, 8, 0, 0, 0, losAngeles); !ok || window.MaxInstances != 4 || !since.Equal(want) {
+		t.Errorf("got max %d since %s, want max 4 since %s", window.MaxInstances, since, want)
+	}
+}
+
+func TestFileNext(t *testing.T) {
+	file, err := Parse("schedule.yaml", []byte(businessHours))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		from time.Time
+		want time.Time
+	}{
+		{from: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 5, 1, 1, 30, 0, 0, time.UTC)},
+		{from: time.Date(2024, 5, 1, 1, 30, 0, 0, time.UTC), want: time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)},
+		{from: time.Date(2024, 5, 3, 20, 0, 0, 0, time.UTC), want: time.Date(2024, 5, 4, 1, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := file.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse("schedule.yaml", []byte(`timezone: Mars/Olympus
+schedules:
+  - windows:
+      - cron: "0 25 * * *"
+        minInstances: 3
+        maxInstances: 2
+  - functionId: fid
+`))
+	if err == nil {
+		t.Fatal("got no error")
+	}
+	for _, want := range []string{
+		`schedule.yaml: timezone: unknown timezone "Mars/Olympus"`,
+		"schedule.yaml: schedules[0].functionId: is required",
+		"schedule.yaml: schedules[0].windows[0].cron: cron expression",
+		"schedule.yaml: schedules[0].windows[0]: minInstances 3 and maxInstances 2",
+		"schedule.yaml: schedules[1].windows: at least one window is required",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got\n%v\nwant it to contain %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Package state keeps what the CLI remembers between runs under ~/.nvcf/state.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tmc/nvcf-go"
+)
+
+// actions recorded in the deployment history
+const (
+	ActionDeployed = "deployed"
+	ActionStopped  = "stopped"
+)
+
+// maxEvents is the number of events kept per function; older ones are dropped
+const maxEvents = 100
+
+// Event is a deployment or stop of a function version made through the CLI.
+// Specifications is set for deployments only.
+type Event struct {
+	VersionID      string                                                     `json:"versionId"`
+	Action         string                                                     `json:"action"`
+	At             time.Time                                                  `json:"at"`
+	Specifications []nvcf.DeploymentResponseDeploymentDeploymentSpecification `json:"specifications,omitempty"`
+}
+
+type history struct {
+	FunctionID string  `json:"functionId"`
+	Events     []Event `json:"events"`
+}
+
+var mu sync.Mutex
+
+// RecordDeployment adds a deployment of a function version, with the
+// specifications it was deployed with, to the history of the function
+func RecordDeployment(functionID, versionID string, specs []nvcf.DeploymentResponseDeploymentDeploymentSpecification) error {
+	return record(functionID, Event{VersionID: versionID, Action: ActionDeployed, At: time.Now().UTC(), Specifications: specs})
+}
+
+// RecordStop adds a stop of a function version to the history of the function
+func RecordStop(functionID, versionID string) error {
+	return record(functionID, Event{VersionID: versionID, Action: ActionStopped, At: time.Now().UTC()})
+}
+
+// History returns the recorded events of a function, oldest first. A function
+// without history has no events.
+func History(functionID string) ([]Event, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	h, err := read(functionID)
+	if err != nil {
+		return nil, err
+	}
+	return h.Events, nil
+}
+
+// LastDeployment returns the most recent recorded deployment of a function version
+func LastDeployment(events []Event, versionID string) (Event, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].VersionID == versionID && events[i].Action == ActionDeployed {
+			return events[i], true
+		}
+	}
+	return Event{}, false
+}
+
+func record(functionID string, event Event) error {
+	mu.Lock()
+	defer mu.Unlock()
+	h, err := read(functionID)
+	if err != nil {
+		return err
+	}
+	h.FunctionID = functionID
+	h.Events = append(h.Events, event)
+	if len(h.Events) > maxEvents {
+		h.Events = h.Events[len(h.Events)-maxEvents:]
+	}
+	path, err := historyPath(functionID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func read(functionID string) (history, error) {
+	var h history
+	path, err := historyPath(functionID)
+	if err != nil {
+		return h, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	err = json.Unmarshal(data, &h)
+	return h, err
+}
+
+// historyPath is ~/.nvcf/state/<function-id>.json
+func historyPath(functionID string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".nvcf", "state", filepath.Base(functionID)+".json"), nil
+}
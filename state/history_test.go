@@ -0,0 +1,70 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tmc/nvcf-go"
+)
+
+func TestHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	events, err := History("fid")
+	if err != nil || len(events) != 0 {
+		t.Fatalf("got %v, %v, want no events for a function without history", events, err)
+	}
+
+	specs := []nvcf.DeploymentResponseDeploymentDeploymentSpecification{{GPU: "L40", InstanceType: "gl40_1.br20_2xlarge", MinInstances: 1, MaxInstances: 2}}
+	if err := RecordDeployment("fid", "v1", specs); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDeployment("fid", "v2", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordStop("fid", "v1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordDeployment("other", "v9", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = History("fid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, event := range events {
+		got = append(got, event.VersionID+" "+event.Action)
+	}
+	if want := []string{"v1 deployed", "v2 deployed", "v1 stopped"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	last, ok := LastDeployment(events, "v1")
+	if !ok || len(last.Specifications) != 1 || last.Specifications[0].GPU != "L40" || last.Specifications[0].MaxInstances != 2 {
+		t.Errorf("got %+v, %v, want the deployment of v1 with its specifications", last, ok)
+	}
+	if _, ok := LastDeployment(events, "v3"); ok {
+		t.Error("got a deployment of v3, which was never deployed")
+	}
+}
+
+func TestHistoryKeepsLatestEvents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for i := 0; i < maxEvents+5; i++ {
+		if err := RecordStop("fid", "v1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := RecordDeployment("fid", "v2", nil); err != nil {
+		t.Fatal(err)
+	}
+	events, err := History("fid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != maxEvents || events[len(events)-1].VersionID != "v2" {
+		t.Errorf("got %d events ending with %+v, want %d ending with the deployment of v2", len(events), events[len(events)-1], maxEvents)
+	}
+}